@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// baseTargets are the targets every generated Makefile has, whatever its
+// features.
+var baseTargets = []string{"all", "bootstrap", "build", "check-make", "clean", "fmt", "generate", "help", "help-json", "lint", "makefile-test", "run", "vet"}
+
+// langTargets are the targets of the -lang flavors.
+var langTargets = map[string][]string{
+	"go+node":   {"npm-install", "npm-build", "npm-test"},
+	"go+python": {"venv", "py-lint", "py-fmt"},
+}
+
+// adopt brings the existing project in a directory under maker management by
+// recognizing the features of its Makefile, writing them to a config and lock
+// file and reporting the targets maker does not manage.
+func adopt(args []string) {
+	flags := flag.NewFlagSet("adopt", flag.ExitOnError)
+	flags.BoolVar(&plain, "plain", false, "Prints the recognized targets without colors or glyphs")
+	flags.BoolVar(&dryRun, "dry-run", false, "Prints what would be recognized without writing the config and lock files")
+	flags.Parse(args)
+
+	if len(flags.Args()) > 1 {
+		fmt.Println("Expected use: maker adopt [-dry-run] [-plain] [DIR]")
+		os.Exit(1)
+	}
+	dir := "."
+	if len(flags.Args()) == 1 {
+		dir = flags.Arg(0)
+	}
+
+	if err := adoptProject(dir); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// adoptProject writes the config and lock files of the project in dir from
+// the features recognized in its Makefile.
+func adoptProject(dir string) error {
+	if exists(filepath.Join(dir, lockFile)) {
+		return fmt.Errorf("%s is already managed by maker, its %s exists", dir, lockFile)
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "Makefile"))
+	if err != nil {
+		return err
+	}
+
+	t := projectType(dir)
+	config := map[string]interface{}{"type": t}
+	managed := append([]string{}, baseTargets...)
+	var options []string
+	for _, target := range makefileTargets(contents) {
+		if f, ok := featureOfTarget(target); ok {
+			if !contains(options, f.name) {
+				options = append(options, f.name)
+				report(passed, fmt.Sprintf("%s is the %s feature", target, f.name))
+			}
+			managed = append(managed, f.targets...)
+			continue
+		}
+		if lang := langOfTarget(target); lang != "" {
+			if config["lang"] == nil {
+				config["lang"] = lang
+				report(passed, fmt.Sprintf("%s is the %s lang", target, lang))
+			}
+			managed = append(managed, langTargets[lang]...)
+		}
+	}
+	// A project without a main package is a library even when its Makefile
+	// has no apidiff target.
+	if t != "monorepo" && isLibrary(dir) && !contains(options, "library") {
+		options = append(options, "library")
+		report(passed, "no main package, the library feature")
+	}
+	for _, target := range makefileTargets(contents) {
+		if !contains(managed, target) {
+			report(skipped, target+" is not managed by maker, keep it in a block override")
+		}
+	}
+
+	sort.Strings(options)
+	for _, option := range options {
+		config[option] = true
+	}
+	if module, err := modulePath(filepath.Join(dir, "go.mod")); err == nil && module != "" {
+		config["mod"] = module
+	}
+	if dryRun {
+		return nil
+	}
+
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, projectConfigFile)
+	if exists(path) {
+		report(skipped, path+" exists, add the options above to it by hand")
+	} else if err := ioutil.WriteFile(path, out, 0644); err != nil {
+		return err
+	} else {
+		report(created, path)
+	}
+
+	adopted := &lock{Version: Version, Type: t, Options: options}
+	if out, err = adopted.bytes(); err != nil {
+		return err
+	}
+	path = filepath.Join(dir, lockFile)
+	if err := ioutil.WriteFile(path, out, 0644); err != nil {
+		return err
+	}
+	report(created, path)
+	return nil
+}
+
+// makefileTargets returns the explicit targets of the rules of a Makefile, in
+// the order they are defined, leaving out special targets, pattern rules and
+// targets named by variables.
+func makefileTargets(contents []byte) []string {
+	var targets []string
+	continued := false
+	for _, line := range strings.Split(string(contents), "\n") {
+		wasContinued := continued
+		continued = strings.HasSuffix(strings.TrimRight(line, " \t\r"), "\\")
+		if wasContinued || strings.HasPrefix(line, "\t") || makeAssignment.MatchString(strings.TrimSpace(line)) || !makeRule.MatchString(line) {
+			continue
+		}
+		i := strings.Index(line, ":")
+		for _, target := range strings.Fields(line[:i]) {
+			if strings.HasPrefix(target, ".") || strings.ContainsAny(target, "%$") || contains(targets, target) {
+				continue
+			}
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// featureOfTarget returns the feature adding the Makefile target.
+func featureOfTarget(target string) (feature, bool) {
+	for _, f := range features {
+		if contains(f.targets, target) {
+			return f, true
+		}
+	}
+	return feature{}, false
+}
+
+// langOfTarget returns the -lang flavor adding the Makefile target, or an
+// empty string when none does.
+func langOfTarget(target string) string {
+	for lang, targets := range langTargets {
+		if contains(targets, target) {
+			return lang
+		}
+	}
+	return ""
+}
+
+// projectType guesses the type of the project in dir: a monorepo has a
+// go.work file and an http project a main.go serving HTTP.
+func projectType(dir string) string {
+	if exists(filepath.Join(dir, "go.work")) {
+		return "monorepo"
+	}
+	contents, _ := ioutil.ReadFile(filepath.Join(dir, "main.go"))
+	if strings.Contains(string(contents), `"net/http"`) {
+		return "http"
+	}
+	return "cli"
+}
+
+// isLibrary reports whether dir has Go files outside its tests and none of
+// them is in the main package.
+func isLibrary(dir string) bool {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	library := false
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil || strings.Contains("\n"+string(contents), "\npackage main") {
+			return false
+		}
+		library = true
+	}
+	return library
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// adrDir holds the architecture decision records of a project generated with
+// -adr.
+const adrDir = "docs/adr"
+
+// adrTemplateFile is the record maker adr new copies, with NUMBER, TITLE and
+// DATE replaced.
+const adrTemplateFile = "template.md"
+
+var (
+	// adrNumber matches the number a record file name starts with.
+	adrNumber = regexp.MustCompile(`^(\d{4})-.*\.md$`)
+	// adrSlugBreak matches the runs of characters the file name of a record
+	// replaces with a dash.
+	adrSlugBreak = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// The templates of the records, rendered into adrDir by -adr.
+func init() {
+	fileTemplates["adr/template.md"] = `# NUMBER. TITLE
+
+Date: DATE
+
+## Status
+
+Proposed
+
+## Context
+
+What is the issue motivating this decision or change?
+
+## Decision
+
+What is the change being proposed or done?
+
+## Consequences
+
+What becomes easier or harder to do because of this change?
+`
+
+	fileTemplates["adr/0001-use-maker-generated-build-tooling.md"] = `# 1. Use maker-generated build tooling
+
+Date: {{.date}}
+
+## Status
+
+Accepted
+
+## Context
+
+{{.name}} needs a way to build, test and lint it that works the same on every
+machine and in CI, without each contributor learning a different set of
+commands.
+
+## Decision
+
+The build tooling of {{.name}} is generated by maker: a Makefile whose
+targets are listed by make help, with the tools pinned in tools.yaml.
+Regenerating the project with maker -force updates the managed part of the
+generated files, and targets of our own go below their preserve marker.
+
+## Consequences
+
+Contributors run the same make targets locally and in CI. Changing the
+generated files above their preserve marker is undone by the next
+regeneration, so such changes go into maker presets or block overrides
+instead.
+`
+}
+
+// adr runs maker adr, which only has the new command.
+func adr(args []string) {
+	if len(args) == 0 || args[0] != "new" {
+		fmt.Println(`Expected use: maker adr new [-dir DIR] "TITLE"`)
+		os.Exit(1)
+	}
+	flags := flag.NewFlagSet("adr new", flag.ExitOnError)
+	dir := flags.String("dir", adrDir, "The directory of the records")
+	flags.BoolVar(&plain, "plain", false, "Prints the new record without colors or glyphs")
+	flags.Parse(args[1:])
+
+	if len(flags.Args()) != 1 || strings.TrimSpace(flags.Arg(0)) == "" {
+		fmt.Println(`Expected use: maker adr new [-dir DIR] "TITLE"`)
+		os.Exit(1)
+	}
+	path, err := newADR(*dir, strings.TrimSpace(flags.Arg(0)))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	report(created, path)
+}
+
+// newADR writes the record titled title into dir, numbered after the last one
+// and copied from its template.md or the built-in template, and returns its
+// path.
+func newADR(dir, title string) (string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%s does not exist, generate the project with -adr or set -dir", dir)
+	}
+	if err != nil {
+		return "", err
+	}
+	last := 0
+	for _, e := range entries {
+		if m := adrNumber.FindStringSubmatch(e.Name()); m != nil {
+			if n, _ := strconv.Atoi(m[1]); n > last {
+				last = n
+			}
+		}
+	}
+
+	template := fileTemplates["adr/"+adrTemplateFile]
+	if contents, err := ioutil.ReadFile(filepath.Join(dir, adrTemplateFile)); err == nil {
+		template = string(contents)
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	number := last + 1
+	record := strings.NewReplacer(
+		"NUMBER", strconv.Itoa(number),
+		"TITLE", title,
+		"DATE", time.Now().Format("2006-01-02"),
+	).Replace(template)
+
+	slug := strings.Trim(adrSlugBreak.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	path := filepath.Join(dir, fmt.Sprintf("%04d-%s.md", number, slug))
+	if exists(path) {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+	return path, ioutil.WriteFile(path, []byte(record), 0644)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// auditedProject is a project maker audit found, with what its lock file
+// records it was generated with.
+type auditedProject struct {
+	Dir       string   `json:"dir"`
+	Maker     string   `json:"maker"`
+	Channel   string   `json:"channel"`
+	Templates string   `json:"templates,omitempty"`
+	Preset    string   `json:"preset,omitempty"`
+	Type      string   `json:"type,omitempty"`
+	Options   []string `json:"options"`
+}
+
+// auditCommand runs maker audit, reporting the maker version and templates
+// every project below the directories was generated with, for tracking the
+// rollout of template fixes across many repositories. The lock files and
+// directories it cannot read are reported after the projects, and fail the
+// audit.
+func auditCommand(args []string) {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Prints the projects as a JSON array")
+	flags.Parse(args)
+
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	projects, errs := audit(dirs)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(projects); err != nil {
+			errs = append(errs, err)
+		}
+	} else {
+		printAudit(projects)
+	}
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
+
+// printAudit prints a line per project followed by how many were generated
+// with each maker version and templates.
+func printAudit(projects []auditedProject) {
+	if len(projects) == 0 {
+		fmt.Println("No generated projects found")
+		return
+	}
+	generations := map[string]int{}
+	for _, p := range projects {
+		// Lock files written before the templates were recorded have none.
+		templates := "unrecorded"
+		if len(p.Templates) >= 12 {
+			templates = p.Templates[:12]
+		}
+		fmt.Printf("%-40s maker %-10s %-8s templates %-12s %s\n", p.Dir, p.Maker, p.Channel, templates, p.Preset)
+		generations[fmt.Sprintf("maker %s, templates %s", p.Maker, templates)]++
+	}
+	var keys []string
+	for key := range generations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Println()
+	for _, key := range keys {
+		fmt.Printf("%4d generated with %s\n", generations[key], key)
+	}
+}
+
+// audit returns the projects below dirs with a lock file, sorted by their
+// directory, along with the errors reading the lock files and directories it
+// could not, which it searches past. The directories of installed tools and
+// dependencies are not searched.
+func audit(dirs []string) ([]auditedProject, []error) {
+	var projects []auditedProject
+	var errs []error
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+			if info.IsDir() {
+				switch info.Name() {
+				case ".git", "bin", "node_modules", "vendor", ".cache", ".venv":
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.Name() != lockFile {
+				return nil
+			}
+			l, err := readLock(p)
+			if err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+			project := auditedProject{
+				Dir:       filepath.Dir(p),
+				Maker:     l.Version,
+				Channel:   l.Channel,
+				Templates: l.Templates,
+				Preset:    l.Preset,
+				Type:      l.Type,
+				Options:   append([]string{}, l.Options...),
+			}
+			if project.Channel == "" {
+				project.Channel = "stable"
+			}
+			projects = append(projects, project)
+			return nil
+		})
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Dir < projects[j].Dir })
+	return projects, errs
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	dir := t.TempDir()
+	for path, contents := range map[string]string{
+		"a/" + lockFile:      "version: v1.2.0\n",
+		"broken/" + lockFile: "version: [\n",
+		"c/" + lockFile:      "version: v1.3.0\nchannel: canary\n",
+	} {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	projects, errs := audit([]string{dir, filepath.Join(dir, "missing")})
+	var got []string
+	for _, p := range projects {
+		got = append(got, filepath.Base(p.Dir)+" "+p.Maker+" "+p.Channel)
+	}
+	if want := "a v1.2.0 stable, c v1.3.0 canary"; strings.Join(got, ", ") != want {
+		t.Errorf("audit found %q, want %q", strings.Join(got, ", "), want)
+	}
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "broken") || !strings.Contains(errs[1].Error(), "missing") {
+		t.Errorf("audit errors = %v, want the broken lock file and the missing directory", errs)
+	}
+}
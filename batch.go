@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// batchSpec is a file describing several projects to generate in one run.
+type batchSpec struct {
+	// Defaults is the config shared by every project.
+	Defaults map[string]interface{} `yaml:"defaults"`
+	// Projects is the config of each project, overriding the defaults. The
+	// name key is required and dir defaults to the name.
+	Projects []map[string]interface{} `yaml:"projects"`
+}
+
+// batchSchema returns the schema of a batch spec, built from the config
+// schema.
+func batchSchema() *schema {
+	config := configSchema()
+	project := *config
+	project.Properties = map[string]*schema{
+		"name": {Type: "string", Description: "The project name."},
+		"dir":  {Type: "string", Description: "The directory to generate into. Defaults to the name."},
+	}
+	for key, property := range config.Properties {
+		project.Properties[key] = property
+	}
+	project.Required = []string{"name"}
+
+	closed := false
+	return &schema{
+		Type: "object",
+		Properties: map[string]*schema{
+			"defaults": config,
+			"projects": {Type: "array", Items: &project},
+		},
+		Required:             []string{"projects"},
+		AdditionalProperties: &closed,
+	}
+}
+
+// batch generates every project of the spec file at path. Each project merges
+// defaults, the spec defaults, its own entry and overrides, in that order. All
+// projects are attempted and the failures reported together, unless ctx is
+// done first.
+func batch(ctx context.Context, path string, defaults []map[string]interface{}, overrides map[string]interface{}, keys []trustedKey) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(contents, &node); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if errs := batchSchema().validate(&node, "", "#"); len(errs) > 0 {
+		return &configError{path, errs}
+	}
+	var spec batchSpec
+	if err := node.Decode(&spec); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	var failures []string
+	for _, entry := range spec.Projects {
+		name := entry["name"].(string)
+		dir, _ := entry["dir"].(string)
+		if dir == "" {
+			dir = name
+		}
+		delete(entry, "name")
+		delete(entry, "dir")
+
+		config := mergeConfigs(append(defaults, spec.Defaults, entry, overrides)...)
+		if err := newProject(ctx, dir, name, config, keys); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted after %d of %d projects:\n%s", len(failures), len(spec.Projects), strings.Join(failures, "\n"))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d projects failed:\n%s", len(failures), len(spec.Projects), strings.Join(failures, "\n"))
+	}
+	return nil
+}
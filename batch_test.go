@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBatchBundles(t *testing.T) {
+	defer func(old bool) { noExec = old }(noExec)
+	noExec = true
+
+	const source = "https://maker.invalid/batch/bundle.yaml"
+	body := []byte(`templates:
+  README.md: "org readme {{.name}}\n"
+makefile: |
+  {{define "run"}}run: phony ## runs the org way
+  	@echo org{{end}}
+policy:
+  require: [test]
+`)
+	remember(source, body)
+	remember(source+".sha256", []byte(sha256Sum(body)+"  bundle.yaml\n"))
+
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "projects.yaml")
+	err := ioutil.WriteFile(spec, []byte(`projects:
+  - {name: org, dir: `+filepath.Join(dir, "org")+`, presetSource: `+source+`, test: true}
+  - {name: plain, dir: `+filepath.Join(dir, "plain")+`}
+  - {name: again, dir: `+filepath.Join(dir, "again")+`, presetSource: `+source+`, test: true}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := batch(context.Background(), spec, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name, file string) string {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, name, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(contents)
+	}
+	if readme := read("org", "README.md"); !strings.HasPrefix(readme, "org readme org") {
+		t.Errorf("org/README.md = %q, want the README of the bundle", readme)
+	}
+	if readme := read("plain", "README.md"); strings.Contains(readme, "org readme") {
+		t.Errorf("plain/README.md = %q, want the built-in README", readme)
+	}
+	if makefile := read("plain", "Makefile"); strings.Contains(makefile, "the org way") {
+		t.Error("plain/Makefile has the Makefile sections of the bundle")
+	}
+	if makefile := read("again", "Makefile"); strings.Count(makefile, "the org way") != 1 {
+		t.Error("again/Makefile lacks the Makefile sections of the bundle")
+	}
+
+	locks := map[string]*lock{}
+	for _, name := range []string{"org", "plain", "again"} {
+		l, err := readLock(filepath.Join(dir, name, lockFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		locks[name] = l
+	}
+	if len(locks["plain"].Sources) != 0 {
+		t.Errorf("plain records the sources %v, want none", locks["plain"].Sources)
+	}
+	if locks["org"].Templates != locks["again"].Templates {
+		t.Errorf("the projects of one bundle record the templates %s and %s, want the same", locks["org"].Templates, locks["again"].Templates)
+	}
+	if locks["org"].Templates == locks["plain"].Templates {
+		t.Error("a project of the bundle records the built-in templates")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// buildDocFile documents the build of a project generated with -report-md.
+const buildDocFile = "docs/BUILD.md"
+
+// toolSentinel matches the prerequisite installing a tool into BIN, capturing
+// the name of the tool.
+var toolSentinel = regexp.MustCompile(`^\$\(BIN\)/\.([A-Za-z0-9_.-]+)-\$\([A-Z0-9_]+_VERSION\)$`)
+
+// toolVersionCall matches the value of a variable holding the version of a
+// tool, capturing the name of the tool.
+var toolVersionCall = regexp.MustCompile(`^\$\(call tool-version,([^)]+)\)$`)
+
+// buildDoc returns the Markdown document of the rendered Makefile of the
+// project: every target make help lists with what it does, the commands it
+// needs on PATH and the tools it installs into BIN, then every variable with
+// its default and the comment describing it.
+func buildDoc(makefile []byte, data map[string]interface{}) []byte {
+	m := parseMakefile(makefile)
+	versions := map[string]string{}
+	for _, t := range enabledTools(data) {
+		versions[t["name"]] = t["version"]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Building %s\n\n", data["name"])
+	fmt.Fprintf(&b, "The Makefile of %s is generated by maker, which regenerates this document with it. Run `make help` for the targets and `make requirements` for the commands missing from PATH.\n", data["name"])
+
+	b.WriteString("\n## Targets\n\n")
+	b.WriteString("| Target | Purpose | Commands | Tools |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, t := range m.targets {
+		if t.help == "" {
+			continue
+		}
+		var tools []string
+		for _, prereq := range t.prereqs {
+			if s := toolSentinel.FindStringSubmatch(prereq); s != nil {
+				tool := s[1]
+				if version := versions[tool]; version != "" {
+					tool += " " + version
+				}
+				tools = append(tools, tool)
+			}
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", t.name, markdownCell(t.help), markdownCell(strings.Join(m.needs[t.name], ", ")), markdownCell(strings.Join(tools, ", ")))
+	}
+
+	comments := variableComments(makefile)
+	b.WriteString("\n## Variables\n\n")
+	b.WriteString("Set them on the command line, as in `make build BIN=/tmp/bin`, or in the environment for those assigned with `?=`.\n\n")
+	b.WriteString("| Variable | Default | Description |\n")
+	b.WriteString("| --- | --- | --- |\n")
+	seen := map[string]bool{}
+	for _, v := range m.vars {
+		if seen[v.name] {
+			continue
+		}
+		seen[v.name] = true
+		value := ""
+		if v.value != "" {
+			value = "`" + markdownCell(v.value) + "`"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", v.name, value, markdownCell(comments[v.name]))
+	}
+	return []byte(b.String())
+}
+
+// variableComments returns the comment above the assignment of each variable
+// of a Makefile, joined into one line.
+func variableComments(makefile []byte) map[string]string {
+	comments := map[string]string{}
+	var block []string
+	// described is set once the comment block has described an assignment.
+	described := false
+	for _, line := range strings.Split(string(makefile), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# "):
+			// The requires comments and markers annotate the rule below.
+			if strings.HasPrefix(line, requiresComment) || strings.Contains(line, preserveMarker) {
+				block = nil
+				continue
+			}
+			if described {
+				block, described = nil, false
+			}
+			block = append(block, strings.TrimPrefix(line, "# "))
+			continue
+		case strings.HasPrefix(line, "\t"):
+		default:
+			if a := makeAssignment.FindStringSubmatch(line); a != nil {
+				fields := strings.Fields(a[1])
+				name := fields[len(fields)-1]
+				// A comment describes the assignment after it and those
+				// following that it names.
+				comment := strings.Join(block, " ")
+				if described && !strings.Contains(comment, name) {
+					block, comment = nil, ""
+				}
+				if tool := toolVersionCall.FindStringSubmatch(a[3]); tool != nil && comment == "" {
+					comment = "the version of " + tool[1] + " pinned in " + toolsFile
+				}
+				if _, ok := comments[name]; !ok && comment != "" {
+					comments[name] = comment
+				}
+				if len(block) > 0 {
+					described = true
+					continue
+				}
+			}
+		}
+		block, described = nil, false
+	}
+	return comments
+}
+
+// markdownCell escapes text for a cell of a Markdown table.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "|", `\|`), "\n", " ")
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	goDirectiveLine     = regexp.MustCompile(`(?m)^go[ \t]+[0-9.]+[ \t]*$`)
+	golangImage         = regexp.MustCompile(`((?:^|[\s"'/])golang:)([0-9]+(?:\.[0-9]+)*)`)
+	goVersionKey        = regexp.MustCompile(`(?m)^(\s*(?:-\s+)?go-version:\s*["']?)([0-9]+(?:\.[0-9]+)*)(["']?\s*)$`)
+	matrixItem          = regexp.MustCompile(`(?m)^(\s*-\s+["']?)([0-9]+(?:\.[0-9]+)*)(["']?\s*)$`)
+	toolVersion         = regexp.MustCompile(`(?m)^((?:golang|go)[ \t]+)(\S+)`)
+	devcontainerGo      = regexp.MustCompile(`(devcontainers/go:[0-9]+-)([0-9]+(?:\.[0-9]+)*)`)
+	devcontainerFeature = regexp.MustCompile(`("ghcr\.io/devcontainers/features/go:[0-9]+"\s*:\s*\{[^}]*"version"\s*:\s*")([^"]+)`)
+)
+
+// bumpGoCommand runs maker bump-go, moving every file of a project naming the
+// go version it builds with to a new version together.
+func bumpGoCommand(args []string) {
+	flags := flag.NewFlagSet("bump-go", flag.ExitOnError)
+	flags.BoolVar(&plain, "plain", false, "Prints the updated files without colors or glyphs")
+	flags.BoolVar(&dryRun, "dry-run", false, "Prints the files that would be updated without writing them")
+	flags.Parse(args)
+
+	if len(flags.Args()) < 1 || len(flags.Args()) > 2 {
+		fmt.Println("Expected use: maker bump-go [-dry-run] [-plain] VERSION [DIR]")
+		os.Exit(1)
+	}
+	version := strings.TrimPrefix(flags.Arg(0), "go")
+	dir := "."
+	if len(flags.Args()) == 2 {
+		dir = flags.Arg(1)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	fsys, err := bumpGo(dir, version)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(fsys.paths) == 0 {
+		report(skipped, "no file of "+dir+" names a go version to update")
+		return
+	}
+	status := updated
+	if dryRun {
+		status = wouldUpdate
+	} else if err := fsys.overwrite(ctx, dir); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for _, p := range fsys.paths {
+		report(status, filepath.Join(dir, filepath.FromSlash(p)))
+	}
+}
+
+// bumpGo returns the files of the project in dir with the go version they
+// name set to version: the go directive of go.mod and go.work, the golang
+// base images of the Dockerfiles, the go version of .tool-versions and of the
+// devcontainer, and the CI jobs running the go directive of go.mod. Other go
+// versions a CI matrix tests with are left as they are.
+func bumpGo(dir, version string) (*memFS, error) {
+	if !goVersion.MatchString(version) {
+		return nil, fmt.Errorf("%s is not a go version, expected one such as 1.22 or 1.22.3", version)
+	}
+	current, err := goDirective(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	if current != "" && compareVersions(version, current) < 0 {
+		return nil, fmt.Errorf("go %s is older than go %s of go.mod", version, current)
+	}
+
+	fsys := newMemFS()
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			switch info.Name() {
+			case ".git", "bin", "node_modules", "vendor", ".cache", ".venv":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		bump := goVersionRewrite(rel, current, version)
+		if bump == nil {
+			return nil
+		}
+		contents, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if bumped := bump(string(contents)); bumped != string(contents) {
+			fsys.WriteFile(rel, []byte(bumped), info.Mode().Perm())
+		}
+		return nil
+	})
+	return fsys, err
+}
+
+// goVersionRewrite returns how to set the go version the file at the project
+// path rel names to version, or nil for a file naming none. current is the go
+// directive of go.mod the CI jobs of the minimum go version run.
+func goVersionRewrite(rel, current, version string) func(string) string {
+	base := path.Base(rel)
+	set := func(re *regexp.Regexp, only string) func(string) string {
+		return func(text string) string {
+			return re.ReplaceAllStringFunc(text, func(m string) string {
+				sub := re.FindStringSubmatch(m)
+				if only != "" && sub[2] != only {
+					return m
+				}
+				return sub[1] + version + strings.Join(sub[3:], "")
+			})
+		}
+	}
+	switch {
+	case base == "go.mod" || base == "go.work":
+		return func(text string) string {
+			return goDirectiveLine.ReplaceAllString(text, "go "+version)
+		}
+	case base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile.") || strings.HasSuffix(base, ".Dockerfile"):
+		return set(golangImage, "")
+	case base == ".tool-versions":
+		return set(toolVersion, "")
+	case base == "devcontainer.json" || base == ".devcontainer.json":
+		return func(text string) string {
+			return set(devcontainerFeature, "")(set(devcontainerGo, "")(text))
+		}
+	case isCIConfig(rel):
+		if current == "" {
+			return nil
+		}
+		return func(text string) string {
+			for _, re := range []*regexp.Regexp{golangImage, goVersionKey, matrixItem} {
+				text = set(re, current)(text)
+			}
+			return text
+		}
+	}
+	return nil
+}
+
+// isCIConfig reports whether the project path rel is the config of a CI
+// provider or a workflow of GitHub Actions or Gitea.
+func isCIConfig(rel string) bool {
+	if strings.HasPrefix(rel, ".github/workflows/") || strings.HasPrefix(rel, ".gitea/workflows/") {
+		return path.Ext(rel) == ".yml" || path.Ext(rel) == ".yaml"
+	}
+	for _, p := range ciProviders {
+		if rel == p.File {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// parsedTemplates holds the templates parsed in this run, keyed by name and
+// the checksum of their text, so the projects of a batch parse each template
+// once.
+var parsedTemplates = struct {
+	sync.Mutex
+	templates map[string]*template.Template
+}{templates: map[string]*template.Template{}}
+
+// parseTemplate returns the template called name parsed from text, with the
+// functions that run commands bound to ctx.
+func parseTemplate(ctx context.Context, name, text string) (*template.Template, error) {
+	key := name + "@" + sha256Sum([]byte(text))
+	parsedTemplates.Lock()
+	templ, ok := parsedTemplates.templates[key]
+	if !ok {
+		var err error
+		templ, err = template.New(name).Funcs(templateFuncs(context.Background())).Option("missingkey=error").Parse(text)
+		if err != nil {
+			parsedTemplates.Unlock()
+			return nil, err
+		}
+		parsedTemplates.templates[key] = templ
+	}
+	parsedTemplates.Unlock()
+
+	// Each use gets a clone, so that binding the functions to ctx or adding
+	// block overrides leaves the cached template untouched.
+	clone, err := templ.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return clone.Funcs(templateFuncs(ctx)), nil
+}
+
+// fetchedBodies holds the bodies fetched in this run by URL, so the projects
+// of a batch sharing a presetSource download it once.
+var fetchedBodies = struct {
+	sync.Mutex
+	bodies map[string][]byte
+}{bodies: map[string][]byte{}}
+
+// remember records the body fetched from url for the rest of the run.
+func remember(url string, body []byte) {
+	fetchedBodies.Lock()
+	fetchedBodies.bodies[url] = body
+	fetchedBodies.Unlock()
+}
+
+// cachedSource returns the cached copy of the source at url when it has the
+// checksum recorded in trusted, which needs no revalidation since a changed
+// source would be refused anyway.
+func cachedSource(url string, trusted *lock) ([]byte, bool) {
+	want, ok := trusted.checksum(url)
+	if !ok {
+		return nil, false
+	}
+	cached, err := ioutil.ReadFile(cachePath(url))
+	if err != nil || !strings.EqualFold(sha256Sum(cached), want) {
+		return nil, false
+	}
+	return cached, true
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// channels are the template sets -channel selects from: the templates built
+// into the release of maker, or the canary bundle of those on the main branch
+// of its repository, which become the next release.
+var channels = []string{"stable", "canary"}
+
+// canarySource is the bundle of the canary channel. The canary workflow runs
+// make canary on every push to the main branch and publishes the bundle with
+// its checksum to the canary branch.
+const canarySource = "https://raw.githubusercontent.com/grocky/maker/canary/templates.yaml"
+
+// loadChannel adds the templates of the canary bundle to set, replacing the
+// built-in ones, when channel is canary, recording the bundle and the channel
+// in generated. The canary bundle is verified against its checksum rather
+// than signed with the keys trusted for preset sources, which maker does not
+// hold. Since it moves with the main branch, it is verified against the
+// checksum published with it on every run instead of the one a lock file
+// recorded, which would refuse the next push.
+func loadChannel(ctx context.Context, channel string, generated *lock, set *templateSet) error {
+	switch channel {
+	case "", "stable":
+		return nil
+	case "canary":
+	default:
+		return fmt.Errorf("unknown channel %q, expected stable or canary", channel)
+	}
+	sum, err := loadPresetSource(ctx, canarySource, &lock{}, nil, set)
+	if err != nil {
+		return err
+	}
+	generated.record(canarySource, sum)
+	generated.Channel = channel
+	return nil
+}
+
+// templateBundle runs maker template bundle, writing the built-in templates
+// as a bundle along with its checksum file, as the canary channel fetches it.
+func templateBundle(args []string) {
+	flags := flag.NewFlagSet("template bundle", flag.ExitOnError)
+	out := flags.String("o", "templates.yaml", "Writes the bundle to this file and its checksum next to it")
+	flags.BoolVar(&plain, "plain", false, "Prints the written files without colors or glyphs")
+	flags.Parse(args)
+	if len(flags.Args()) != 0 {
+		fmt.Println("Expected use: maker template bundle [-o FILE]")
+		os.Exit(1)
+	}
+
+	contents, err := yaml.Marshal(bundle{Templates: fileTemplates, Makefile: makefileTemplate})
+	if err == nil {
+		err = ioutil.WriteFile(*out, contents, 0644)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(*out+".sha256", []byte(sha256Sum(contents)+"  "+filepath.Base(*out)+"\n"), 0644)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	report(created, *out)
+	report(created, *out+".sha256")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// requiredCommands matches the REQUIRED_COMMANDS of a generated Makefile.
+	requiredCommands = regexp.MustCompile(`(?m)^REQUIRED_COMMANDS\s*:?=\s*(.*)$`)
+	// makeShell matches the SHELL a Makefile sets for its recipes.
+	makeShell = regexp.MustCompile(`(?m)^SHELL\s*:?=\s*(\S+)`)
+)
+
+// checkMakeCompat reports which targets of the Makefile in a directory would
+// fail with the make, shell, awk and commands of the environment, and why.
+func checkMakeCompat(args []string) {
+	flags := flag.NewFlagSet("check-make", flag.ExitOnError)
+	flags.BoolVar(&plain, "plain", false, "Prints the checks without colors or glyphs")
+	flags.Parse(args)
+
+	if len(flags.Args()) > 1 {
+		fmt.Println("Expected use: maker check-make [-plain] [DIR]")
+		os.Exit(1)
+	}
+	dir := "."
+	if len(flags.Args()) == 1 {
+		dir = flags.Arg(0)
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "Makefile"))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+
+	// A make or shell that cannot run the Makefile fails every target, so
+	// the targets are not checked one by one.
+	compatible := true
+	for _, f := range []finding{checkMake(ctx, dir), checkShell(ctx, contents)} {
+		if f.ok {
+			report(passed, f.text)
+		} else {
+			report(failed, "every target: "+f.text)
+			compatible = false
+		}
+	}
+	if !compatible {
+		os.Exit(1)
+	}
+
+	awk := checkAwk(ctx)
+	if awk.ok {
+		report(passed, awk.text)
+	}
+	if _, err := exec.LookPath("tput"); err != nil {
+		report(skipped, "tput is not on PATH, help prints without colors")
+	}
+
+	var commands []string
+	if m := requiredCommands.FindSubmatch(contents); m != nil {
+		commands = strings.Fields(string(m[1]))
+	}
+	recipes := makefileRecipes(contents)
+	for _, target := range makefileTargets(contents) {
+		var reasons []string
+		if !awk.ok && (target == "help" || target == "help-json" || strings.Contains(recipes[target], "awk ")) {
+			reasons = append(reasons, awk.text)
+		}
+		for _, command := range commands {
+			if command == "awk" || !runsCommand(recipes[target], command) {
+				continue
+			}
+			if _, err := exec.LookPath(command); err != nil {
+				reasons = append(reasons, command+" is not on PATH")
+			}
+		}
+		if len(reasons) > 0 {
+			report(failed, target+": "+strings.Join(reasons, ", "))
+			compatible = false
+		}
+	}
+	if !compatible {
+		os.Exit(1)
+	}
+}
+
+// checkShell checks that the SHELL of a Makefile is installed, and that bash
+// supports the pipefail its recipes are run with.
+func checkShell(ctx context.Context, contents []byte) finding {
+	shell := "/bin/sh"
+	if m := makeShell.FindSubmatch(contents); m != nil {
+		shell = string(m[1])
+	}
+	if _, err := exec.LookPath(shell); err != nil {
+		return finding{false, shell + " is not installed, install it or regenerate with -shell sh"}
+	}
+	if filepath.Base(shell) == "bash" {
+		if _, err := queryCommand(ctx, "", shell, "-c", "set -o pipefail"); err != nil {
+			return finding{false, shell + " does not support pipefail, install a newer bash or regenerate with -shell sh"}
+		}
+	}
+	return finding{true, "SHELL " + shell}
+}
+
+// checkAwk checks that awk matches the rules of the Makefile like the help
+// target does, which the awk of some BusyBox versions does not.
+func checkAwk(ctx context.Context) finding {
+	if _, err := exec.LookPath("awk"); err != nil {
+		return finding{false, "awk is not on PATH"}
+	}
+	flavor := "awk"
+	for _, version := range [][]string{{"--version"}, {"-W", "version"}} {
+		if out, err := queryCommand(ctx, "", "awk", version...); err == nil && out != "" {
+			flavor = strings.TrimSpace(strings.SplitN(out, "\n", 2)[0])
+			break
+		}
+	}
+	cmd := exec.CommandContext(ctx, "awk", "-F", ":|##", `/^[^\t].+?:.*?##/ { print $1 }`)
+	cmd.Stdin = strings.NewReader("build: phony ## build the binary\n")
+	if out, err := cmd.Output(); err != nil || strings.TrimSpace(string(out)) != "build" {
+		return finding{false, flavor + " does not match the rules of the Makefile, install gawk or mawk"}
+	}
+	return finding{true, flavor}
+}
+
+// makefileRecipes returns the recipes of the rules of a Makefile by target.
+func makefileRecipes(contents []byte) map[string]string {
+	recipes := map[string]string{}
+	var current []string
+	continued := false
+	for _, line := range strings.Split(string(contents), "\n") {
+		wasContinued := continued
+		continued = strings.HasSuffix(strings.TrimRight(line, " \t\r"), "\\")
+		switch {
+		case strings.HasPrefix(line, "\t") || wasContinued:
+			for _, target := range current {
+				recipes[target] += line + "\n"
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+		case makeAssignment.MatchString(strings.TrimSpace(line)) || !makeRule.MatchString(line):
+			current = nil
+		default:
+			current = strings.Fields(line[:strings.Index(line, ":")])
+		}
+	}
+	return recipes
+}
+
+// runsCommand reports whether a recipe runs command, as a word following the
+// start of a line, a space or a shell operator.
+func runsCommand(recipe, command string) bool {
+	return regexp.MustCompile(`(^|[\s@;&|(])` + regexp.QuoteMeta(command) + `(\s|$)`).MatchString(recipe)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ciProvider is a CI service, whose config the pipeline is rendered into.
+type ciProvider struct {
+	Name string
+	// File is the path of the config the provider runs, rendered from the
+	// Template.
+	File     string
+	Template string
+	// containers is set for the providers that run CI jobs in images, where
+	// the go versions of the matrix are golang image tags.
+	containers bool
+}
+
+// ciProviders are the CI services -ci-provider selects from. Gitea runs
+// workflows written for GitHub Actions.
+var ciProviders = []ciProvider{
+	{Name: "github", File: ".github/workflows/ci.yml", Template: "ci.yml"},
+	{Name: "gitea", File: ".gitea/workflows/ci.yml", Template: "ci.yml"},
+	{Name: "gitlab", File: ".gitlab-ci.yml", Template: "gitlab-ci.yml", containers: true},
+	{Name: "bitbucket", File: "bitbucket-pipelines.yml", Template: "bitbucket-pipelines.yml", containers: true},
+	{Name: "circleci", File: ".circleci/config.yml", Template: "circleci.yml", containers: true},
+}
+
+// lookupCIProvider returns the CI service with the name.
+func lookupCIProvider(name string) (ciProvider, bool) {
+	for _, p := range ciProviders {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return ciProvider{}, false
+}
+
+// ciProviderNames returns the names of the CI services.
+func ciProviderNames() []string {
+	names := make([]string, len(ciProviders))
+	for i, p := range ciProviders {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// ciStep is a make target the CI pipeline runs in one of its stages, along with
+// the file it leaves behind to keep, if any.
+type ciStep struct {
+	stage    string
+	target   string
+	artifact string
+}
+
+// ciStages are the stages of the pipeline in the order they run.
+var ciStages = []string{"build", "check", "test"}
+
+// baseSteps are run by the pipeline of every project.
+var baseSteps = []ciStep{
+	{stage: "build", target: "build"},
+	{stage: "check", target: "vet"},
+	{stage: "check", target: "lint"},
+}
+
+// ciCache is a directory kept between the runs of the pipeline, relative to
+// the project, which Env points the go command at.
+type ciCache struct {
+	Name string
+	Path string
+	Env  string
+}
+
+// ciCaches are the caches of every pipeline.
+var ciCaches = []ciCache{
+	{Name: "go-build", Path: ".cache/go-build", Env: "GOCACHE"},
+	{Name: "go-mod", Path: ".cache/go-mod", Env: "GOMODCACHE"},
+}
+
+// pipeline is the CI pipeline of a project, modeled once and rendered into
+// the config of its Provider.
+type pipeline struct {
+	Provider ciProvider
+	Stages   []pipelineStage
+	Caches   []ciCache
+	// Artifacts are the files kept from every run, relative to the project.
+	Artifacts []string
+	repo      vcsRepo
+}
+
+// pipelineStage is a stage of the pipeline, running make with each of its
+// Targets in order.
+type pipelineStage struct {
+	Name    string
+	Targets []string
+}
+
+// Badge is the image of the status of the pipeline, empty for the providers
+// and hosts that serve none.
+func (p pipeline) Badge() string {
+	if p.repo.Path == "" {
+		return ""
+	}
+	switch p.Provider.Name {
+	case "github", "gitea":
+		return p.repo.URL() + "/actions/workflows/ci.yml/badge.svg"
+	case "gitlab":
+		return p.repo.URL() + "/badges/main/pipeline.svg"
+	case "circleci":
+		switch p.repo.Host.Name {
+		case "github":
+			return "https://dl.circleci.com/status-badge/img/gh/" + p.repo.Path + "/tree/main.svg"
+		case "bitbucket":
+			return "https://dl.circleci.com/status-badge/img/bb/" + p.repo.Path + "/tree/main.svg"
+		}
+	}
+	return ""
+}
+
+// setPipeline sets the pipeline of the template data from -ci-provider, or the
+// CI of the code host when it is not set, with the steps of the base and of
+// every enabled feature.
+func setPipeline(data map[string]interface{}) error {
+	repo, _ := data["vcs"].(vcsRepo)
+	provider, _ := lookupCIProvider(repo.Host.CI)
+	if name, _ := data["ciProvider"].(string); name != "" {
+		var ok bool
+		if provider, ok = lookupCIProvider(name); !ok {
+			return fmt.Errorf("unknown ci provider %q, expected one of %s", name, strings.Join(ciProviderNames(), ", "))
+		}
+	}
+
+	steps := append([]ciStep{}, baseSteps...)
+	for _, f := range enabledFeatures(data) {
+		steps = append(steps, f.ciSteps...)
+	}
+	p := pipeline{Provider: provider, Caches: ciCaches, repo: repo}
+	for _, stage := range ciStages {
+		s := pipelineStage{Name: stage}
+		for _, step := range steps {
+			if step.stage != stage {
+				continue
+			}
+			s.Targets = append(s.Targets, step.target)
+			if step.artifact != "" {
+				p.Artifacts = append(p.Artifacts, step.artifact)
+			}
+		}
+		if len(s.Targets) > 0 {
+			p.Stages = append(p.Stages, s)
+		}
+	}
+	data["pipeline"] = p
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cleanGenerated removes the files a project was generated with, as recorded in
+// its lock file, keeping those modified since unless -force is given.
+func cleanGenerated(args []string) {
+	flags := flag.NewFlagSet("clean-generated", flag.ExitOnError)
+	force := flags.Bool("force", false, "Removes generated files even when they were modified")
+	flags.BoolVar(&plain, "plain", false, "Prints the removed files without colors or glyphs")
+	flags.Parse(args)
+
+	if len(flags.Args()) > 1 {
+		fmt.Println("Expected use: maker clean-generated [-force] [DIR]")
+		os.Exit(1)
+	}
+	dir := "."
+	if len(flags.Args()) == 1 {
+		dir = flags.Arg(0)
+	}
+
+	if err := clean(dir, *force); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// clean removes the generated files of the project in dir along with the
+// directories left empty. Protected files are kept even with force, and so
+// are modified files without it. The lock file is removed last, unless any
+// file was kept, in which case it is rewritten to list only those.
+func clean(dir string, force bool) error {
+	path := filepath.Join(dir, lockFile)
+	generated, err := readLock(path)
+	if err != nil {
+		return err
+	}
+	if len(generated.Files) == 0 {
+		return fmt.Errorf("%s: no generated files recorded", path)
+	}
+
+	protected, err := readProtection(dir)
+	if err != nil {
+		return err
+	}
+
+	var kept []lockedFile
+	dirs := map[string]bool{}
+	for _, f := range generated.Files {
+		file := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if protected.protects(f.Path) {
+			report(skipped, file+" is protected")
+			kept = append(kept, f)
+			continue
+		}
+		contents, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		managed, user := splitMarkers(f.Path, contents)
+		if !force && sha256Sum(managed) != f.SHA256 {
+			report(skipped, file+" was modified, use -force to remove it")
+			kept = append(kept, f)
+			continue
+		}
+		if !force && len(strings.TrimSpace(string(user))) > 0 {
+			report(skipped, file+" has edits below its preserve marker, use -force to remove it")
+			kept = append(kept, f)
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		report(removed, file)
+		for d := filepath.Dir(f.Path); d != "."; d = filepath.Dir(d) {
+			dirs[d] = true
+		}
+	}
+
+	// Remove the deepest directories first so their parents can empty out.
+	var empty []string
+	for d := range dirs {
+		empty = append(empty, d)
+	}
+	sort.Slice(empty, func(i, j int) bool { return strings.Count(empty[i], "/") > strings.Count(empty[j], "/") })
+	for _, d := range empty {
+		os.Remove(filepath.Join(dir, filepath.FromSlash(d)))
+	}
+
+	if len(kept) > 0 {
+		generated.Files = kept
+		out, err := generated.bytes()
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, out, 0644)
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	report(removed, path)
+	return nil
+}
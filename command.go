@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// commandTimeout bounds how long a single external command may run.
+	commandTimeout = 2 * time.Minute
+	// maxConcurrentCommands bounds how many external commands run at once.
+	maxConcurrentCommands = 4
+)
+
+// noExec prints the external commands that change something instead of
+// running them.
+var noExec bool
+
+// commandSlots holds a token for every external command running.
+var commandSlots = make(chan struct{}, maxConcurrentCommands)
+
+// runCommand runs the external command name with args in dir, the working
+// directory when empty, and returns its standard output. The command is killed
+// when ctx is done. With -no-exec it prints the command and returns no output
+// instead.
+func runCommand(ctx context.Context, dir, name string, args ...string) (string, error) {
+	if noExec {
+		line := commandLine(name, args)
+		if dir != "" {
+			line = fmt.Sprintf("(cd %s && %s)", dir, line)
+		}
+		fmt.Println(line)
+		return "", nil
+	}
+	return queryCommand(ctx, dir, name, args...)
+}
+
+// queryCommand runs the external command name with args in dir like
+// runCommand, but also with -no-exec, for commands that only read the state
+// of the environment. A failure includes the standard error of the command.
+func queryCommand(ctx context.Context, dir, name string, args ...string) (string, error) {
+	select {
+	case commandSlots <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-commandSlots }()
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if parent.Err() != nil {
+		return "", fmt.Errorf("%s: %v", commandLine(name, args), parent.Err())
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("%s: timed out after %s", commandLine(name, args), commandTimeout)
+	}
+	if err != nil {
+		if out := strings.TrimSpace(stderr.String()); out != "" {
+			return "", fmt.Errorf("%s: %v\n%s", commandLine(name, args), err, out)
+		}
+		return "", fmt.Errorf("%s: %v", commandLine(name, args), err)
+	}
+	return stdout.String(), nil
+}
+
+// commandLine returns name and args as they would be typed in a shell.
+func commandLine(name string, args []string) string {
+	words := []string{name}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"$`\\|&;<>(){}*?") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		words = append(words, arg)
+	}
+	return strings.Join(words, " ")
+}
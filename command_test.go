@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestQueryCommand(t *testing.T) {
+	out, err := queryCommand(context.Background(), "", "sh", "-c", "echo out; echo err >&2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "out\n" {
+		t.Errorf("output = %q, want the standard output only", out)
+	}
+
+	_, err = queryCommand(context.Background(), "", "sh", "-c", "echo broken >&2; exit 3")
+	if err == nil {
+		t.Fatal("a failing command succeeded")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "sh -c 'echo broken >&2; exit 3'") || !strings.Contains(msg, "broken") {
+		t.Errorf("error = %q, want the command line and its standard error", msg)
+	}
+}
+
+func TestQueryCommandCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := queryCommand(ctx, "", "sleep", "10"); err == nil {
+		t.Fatal("a canceled command succeeded")
+	}
+}
+
+func TestRunCommandNoExec(t *testing.T) {
+	defer func(old bool) { noExec = old }(noExec)
+	noExec = true
+
+	dir := t.TempDir()
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	out, err := runCommand(context.Background(), dir, "touch", "created")
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := ioutil.ReadAll(r)
+
+	if err != nil || out != "" {
+		t.Fatalf("runCommand = %q, %v, want no output", out, err)
+	}
+	if want := "(cd " + dir + " && touch created)\n"; string(printed) != want {
+		t.Errorf("printed %q, want %q", printed, want)
+	}
+	if _, err := os.Stat(dir + "/created"); !os.IsNotExist(err) {
+		t.Error("-no-exec ran the command")
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"mod", "tidy"}, "go mod tidy"},
+		{[]string{"run", "$HOME"}, "go run '$HOME'"},
+		{[]string{"it's"}, `go 'it'\''s'`},
+		{[]string{""}, "go ''"},
+	} {
+		if got := commandLine("go", c.args); got != c.want {
+			t.Errorf("commandLine(go, %q) = %q, want %q", c.args, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigFile is the config file read from the working directory.
+const projectConfigFile = ".maker.yaml"
+
+// userConfigFile returns the path of the per-user config file,
+// $XDG_CONFIG_HOME/maker/config.yaml or ~/.config/maker/config.yaml.
+func userConfigFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "maker", "config.yaml")
+}
+
+// configError lists the schema violations found in a config file.
+type configError struct {
+	path string
+	errs []schemaError
+}
+
+func (e *configError) Error() string {
+	lines := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		lines[i] = e.path + ":" + err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// loadConfig reads and validates the config file at path. A missing file is
+// an empty config.
+func loadConfig(path string) (map[string]interface{}, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(contents, &node); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(node.Content) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	if errs := configSchema().validate(&node, "", "#"); len(errs) > 0 {
+		return nil, &configError{path, errs}
+	}
+
+	config := map[string]interface{}{}
+	if err := node.Decode(&config); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return config, nil
+}
+
+// projectTypes are the kinds of project maker can generate. A monorepo is a
+// workspace root that services are added to with add-service.
+var projectTypes = []string{"cli", "http", "monorepo"}
+
+// languages are the languages a project can combine, each adding its targets
+// to the makefile.
+var languages = []string{"go", "go+node", "go+python"}
+
+// goVersionAliases are the go versions of the CI matrix besides release
+// numbers: the go directive of go.mod, the two latest releases and the
+// development tree.
+var goVersionAliases = []string{"minimum", "oldstable", "stable", "tip"}
+
+// minGoVersion is the go version of the go directive of the generated go.mod,
+// the minimum of the CI matrix. It is the oldest go that installs every tool
+// the CI jobs run, since the minimum job keeps to it with GOTOOLCHAIN=local.
+// Generating into an existing module keeps the go directive of its go.mod
+// instead.
+const minGoVersion = "1.22"
+
+// goVersion matches a go release number of the CI matrix.
+var goVersion = regexp.MustCompile(`^1\.\d+(\.\d+)?$`)
+
+// assertionLibraries are the libraries the generated tests can be written with.
+// stdlib uses plain comparisons from the testing package.
+var assertionLibraries = []string{"stdlib", "testify", "gotest.tools"}
+
+// envVariables maps the environment variables maker reads to config keys.
+var envVariables = map[string]string{
+	"MAKER_PRESET":        "preset",
+	"MAKER_MODULE_PREFIX": "modulePrefix",
+	"MAKER_LICENSE":       "license",
+	"MAKER_TEMPLATES_DIR": "templatesDir",
+}
+
+// envConfig returns the config set through environment variables.
+func envConfig() map[string]interface{} {
+	config := map[string]interface{}{}
+	for name, key := range envVariables {
+		if value, ok := os.LookupEnv(name); ok {
+			config[key] = value
+		}
+	}
+	return config
+}
+
+// flagConfig returns the config set through the flags explicitly passed to
+// flags. Kebab-case flag names map to camelCase config keys.
+func flagConfig(flags *flag.FlagSet) map[string]interface{} {
+	config := map[string]interface{}{}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "config" || f.Name == "version" || f.Name == "allow-unsafe-functions" || f.Name == "plain" || f.Name == "dry-run" || f.Name == "force" || f.Name == "report" || f.Name == "no-exec" || f.Name == "update-sources" {
+			return
+		}
+		config[configKey(f.Name)] = f.Value.(flag.Getter).Get()
+	})
+	return config
+}
+
+// configKey returns the config key of a flag name, make-features for example
+// becoming makeFeatures.
+func configKey(name string) string {
+	parts := strings.Split(name, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// mergeConfigs merges configs into one, with later configs taking precedence.
+func mergeConfigs(configs ...map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for _, config := range configs {
+		for key, value := range config {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// applyConfig sets the template data from a merged config. The options of a
+// preset are enabled first so that options set in the config override them.
+func applyConfig(data, config map[string]interface{}) error {
+	if preset, _ := config["preset"].(string); preset != "" {
+		options, ok := projectTemplates(data).preset(preset)
+		if !ok {
+			return fmt.Errorf("unknown preset %q", preset)
+		}
+		for _, option := range options {
+			data[option] = true
+		}
+	}
+	if t, _ := config["type"].(string); t != "" && !contains(projectTypes, t) {
+		return fmt.Errorf("unknown type %q, expected one of %s", t, strings.Join(projectTypes, ", "))
+	}
+	if shell, _ := config["shell"].(string); shell != "" && shell != "bash" && shell != "sh" {
+		return fmt.Errorf("unknown shell %q, expected bash or sh", shell)
+	}
+	if format, _ := config["format"].(string); format != "" && !contains(taskFormats, format) {
+		return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(taskFormats, ", "))
+	}
+	if lang, _ := config["lang"].(string); lang != "" && !contains(languages, lang) {
+		return fmt.Errorf("unknown lang %q, expected one of %s", lang, strings.Join(languages, ", "))
+	}
+	if assertions, _ := config["assertions"].(string); assertions != "" && !contains(assertionLibraries, assertions) {
+		return fmt.Errorf("unknown assertions %q, expected one of %s", assertions, strings.Join(assertionLibraries, ", "))
+	}
+	if versions, _ := config["goVersions"].(string); versions != "" {
+		var matrix []string
+		for _, v := range strings.Split(versions, ",") {
+			v = strings.TrimSpace(v)
+			if !contains(goVersionAliases, v) && !goVersion.MatchString(v) {
+				return fmt.Errorf("unknown go version %q, expected a release like 1.22 or one of %s", v, strings.Join(goVersionAliases, ", "))
+			}
+			matrix = append(matrix, v)
+		}
+		config["goVersions"] = strings.Join(matrix, ",")
+	}
+	if strategy, _ := config["strategy"].(string); strategy != "" && !contains(strategies, strategy) {
+		return fmt.Errorf("unknown strategy %q, expected one of %s", strategy, strings.Join(strategies, ", "))
+	}
+	if naming, _ := config["naming"].(string); naming != "" && !contains(namingConventions, naming) {
+		return fmt.Errorf("unknown naming %q, expected one of %s", naming, strings.Join(namingConventions, ", "))
+	}
+	if features, _ := config["makeFeatures"].(string); features != "" && features != "4.x" {
+		return fmt.Errorf("unknown make features %q, expected 4.x", features)
+	}
+	if license, _ := config["license"].(string); license != "" {
+		if _, ok := projectTemplates(data).template("licenses/" + license); !ok {
+			return fmt.Errorf("unknown license %q", license)
+		}
+	}
+
+	for key, value := range config {
+		if key == "mod" {
+			key = "module"
+		}
+		data[key] = value
+	}
+	// The name and binaries are named in the convention before the module
+	// path and the meta derive from them.
+	if naming, _ := data["naming"].(string); naming != "" {
+		data["name"] = applyNaming(data["name"].(string), naming)
+		var binaries []string
+		for _, binary := range strings.Split(data["binaries"].(string), ",") {
+			if binary = strings.TrimSpace(binary); binary != "" {
+				binaries = append(binaries, applyNaming(binary, naming))
+			}
+		}
+		data["binaries"] = strings.Join(binaries, ",")
+	}
+	for _, enabled := range enableRequired(data, config) {
+		if summarize {
+			report(autoEnabled, enabled)
+		}
+	}
+	if err := enforcePolicy(data, config); err != nil {
+		return err
+	}
+	if github, _ := data["github"].(string); github != "" && data["modulePrefix"] == "" {
+		data["modulePrefix"] = "github.com/" + github
+	}
+	if prefix, _ := data["modulePrefix"].(string); prefix != "" && data["module"] == "" {
+		data["module"] = strings.TrimSuffix(prefix, "/") + "/" + data["name"].(string)
+	}
+	if err := setVCS(data); err != nil {
+		return err
+	}
+	if err := setPipeline(data); err != nil {
+		return err
+	}
+	// The golang images have no tags for oldstable and tip.
+	if _, ok := config["goVersions"]; !ok && data["pipeline"].(pipeline).Provider.containers {
+		data["goVersions"] = "minimum,stable"
+	}
+	if err := setLocale(data); err != nil {
+		return err
+	}
+	return setMeta(data)
+}
+
+// validate checks config files against the schema, defaulting to the project
+// and user config files that exist.
+func validate(args []string) {
+	paths := args
+	if len(paths) == 0 {
+		paths = []string{projectConfigFile, userConfigFile()}
+	}
+
+	failed := false
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			if len(args) > 0 {
+				fmt.Println(err)
+				failed = true
+			}
+			continue
+		}
+		if _, err := loadConfig(path); err != nil {
+			fmt.Println(err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestApplyConfig(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		config map[string]interface{}
+		want   map[string]interface{}
+		err    string
+	}{
+		{
+			name:   "preset",
+			config: map[string]interface{}{"preset": "profiling"},
+			want:   map[string]interface{}{"bench": true, "cpuProfile": true, "memProfile": true, "test": false},
+		},
+		{
+			name:   "preset overridden",
+			config: map[string]interface{}{"preset": "profiling", "memProfile": false},
+			want:   map[string]interface{}{"bench": true, "cpuProfile": true, "memProfile": false},
+		},
+		{
+			name:   "unknown preset",
+			config: map[string]interface{}{"preset": "speed"},
+			err:    `unknown preset "speed"`,
+		},
+		{
+			name:   "unknown license",
+			config: map[string]interface{}{"license": "WTFPL"},
+			err:    `unknown license "WTFPL"`,
+		},
+		{
+			name:   "mod",
+			config: map[string]interface{}{"mod": "example.com/m"},
+			want:   map[string]interface{}{"module": "example.com/m"},
+		},
+		{
+			name:   "module prefix",
+			config: map[string]interface{}{"modulePrefix": "example.com/team/"},
+			want:   map[string]interface{}{"module": "example.com/team/project"},
+		},
+		{
+			name:   "mod over module prefix",
+			config: map[string]interface{}{"modulePrefix": "example.com/team", "mod": "example.com/m"},
+			want:   map[string]interface{}{"module": "example.com/m"},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			data := templateData("project")
+			err := applyConfig(data, c.config)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("applyConfig = %v, want an error containing %q", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range c.want {
+				if data[key] != want {
+					t.Errorf("%s = %v, want %v", key, data[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvConfig(t *testing.T) {
+	for name := range envVariables {
+		if value, ok := os.LookupEnv(name); ok {
+			defer os.Setenv(name, value)
+		} else {
+			defer os.Unsetenv(name)
+		}
+		os.Unsetenv(name)
+	}
+	os.Setenv("MAKER_PRESET", "quality")
+	os.Setenv("MAKER_MODULE_PREFIX", "")
+
+	config := envConfig()
+	want := map[string]interface{}{"preset": "quality", "modulePrefix": ""}
+	if len(config) != len(want) {
+		t.Errorf("envConfig = %v, want %v", config, want)
+	}
+	for key, value := range want {
+		if config[key] != value {
+			t.Errorf("%s = %v, want %v", key, config[key], value)
+		}
+	}
+
+	// The config files and flags override the environment.
+	merged := mergeConfigs(config, map[string]interface{}{"preset": "testing"})
+	if merged["preset"] != "testing" || merged["modulePrefix"] != "" {
+		t.Errorf("mergeConfigs = %v, want the later preset", merged)
+	}
+}
@@ -0,0 +1,944 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// convertFormats are the command runners maker convert translates a Makefile
+// for, with the file each one reads.
+var convertFormats = map[string]string{
+	"taskfile": "Taskfile.yml",
+	"justfile": "justfile",
+}
+
+var (
+	// makeConditional matches the lines opening a conditional of a Makefile.
+	makeConditional = regexp.MustCompile(`^(ifeq|ifneq|ifdef|ifndef)\s`)
+	// makeVersionSuffix matches the -$(NAME_VERSION) a tool sentinel ends
+	// with.
+	makeVersionSuffix = regexp.MustCompile(`-\$\([A-Z0-9_]+_VERSION\)$`)
+	// makeArgument matches the $(1) to $(9) of a make function.
+	makeArgument = regexp.MustCompile(`\$\(([1-9])\)`)
+	// taskName matches the characters the names of file tasks keep.
+	taskName = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// convert runs maker convert, which writes the Taskfile or justfile of a
+// generated Makefile.
+func convert(args []string) {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := flags.String("to", "", "Converts the Makefile for this command runner (taskfile, justfile)")
+	out := flags.String("o", "", "Writes the converted file to this path, Taskfile.yml or justfile beside the Makefile by default")
+	overwrite := flags.Bool("force", false, "Overwrites an existing converted file")
+	flags.BoolVar(&plain, "plain", false, "Prints the conversion without colors or glyphs")
+	flags.Parse(args)
+
+	name, ok := convertFormats[*to]
+	if !ok || len(flags.Args()) > 1 {
+		fmt.Println("Expected use: maker convert -to taskfile|justfile [-o FILE] [-force] [MAKEFILE]")
+		os.Exit(1)
+	}
+	makefile := "Makefile"
+	if len(flags.Args()) == 1 {
+		makefile = flags.Arg(0)
+	}
+	if *out == "" {
+		*out = filepath.Join(filepath.Dir(makefile), name)
+	}
+	if exists(*out) && !*overwrite {
+		fmt.Printf("%s already exists, use -force to overwrite it\n", *out)
+		os.Exit(1)
+	}
+
+	contents, err := ioutil.ReadFile(makefile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	m := parseMakefile(contents)
+	var converted []byte
+	if *to == "taskfile" {
+		converted, err = m.taskfile()
+	} else {
+		converted, err = m.justfile()
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(*out, converted, 0644); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for _, s := range m.skipped {
+		report(skipped, s)
+	}
+	report(created, *out)
+}
+
+// makefileModel is a Makefile as maker convert reads it: its variables,
+// functions and rules, leaving out the conditionals, which neither command
+// runner has.
+type makefileModel struct {
+	defaultGoal string
+	shell       string
+	shellFlags  []string
+	oneShell    bool
+	vars        []makeVar
+	// functions are the variables and define blocks called with $(call),
+	// as the lines $(1) to $(9) are substituted into.
+	functions map[string][]string
+	targets   []*makeTarget
+	// needs are the commands the requires comment above each target lists.
+	needs   map[string][]string
+	skipped []string
+}
+
+// makeVar is a variable of a Makefile.
+type makeVar struct {
+	name   string
+	value  string
+	export bool
+}
+
+// makeTarget is a rule of a Makefile. file is set for the rules making a
+// file rather than a phony target, such as the sentinels of the tools.
+type makeTarget struct {
+	name    string
+	help    string
+	prereqs []string
+	recipe  []string
+	file    bool
+}
+
+// parseMakefile reads the variables, functions and rules of a Makefile.
+func parseMakefile(contents []byte) *makefileModel {
+	m := &makefileModel{functions: map[string][]string{}, needs: map[string][]string{}}
+	for command, targets := range targetRequirements(contents) {
+		for _, target := range targets {
+			m.needs[target] = append(m.needs[target], command)
+		}
+	}
+	lines := strings.Split(string(contents), "\n")
+	var current []*makeTarget
+	depth := 0
+	var define string
+	var conditional []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		// Continued lines are read as one.
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) && !strings.HasPrefix(line, "\t") {
+			i++
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(lines[i])
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case define != "":
+			if trimmed == "endef" {
+				define = ""
+			} else {
+				m.functions[define] = append(m.functions[define], line)
+			}
+			continue
+		case strings.HasPrefix(trimmed, "define "):
+			define = strings.TrimSpace(strings.TrimPrefix(trimmed, "define "))
+			continue
+		case makeConditional.MatchString(trimmed):
+			depth++
+			current = nil
+			continue
+		case trimmed == "endif":
+			depth--
+			continue
+		case depth > 0:
+			if r := makeRule.FindStringSubmatch(line); r != nil && !strings.HasPrefix(line, "\t") && !makeAssignment.MatchString(trimmed) && trimmed != "else" {
+				conditional = append(conditional, strings.Fields(line[:strings.Index(line, ":")])...)
+			}
+			continue
+		case strings.HasPrefix(line, "\t"):
+			recipe := strings.TrimPrefix(line, "\t")
+			for strings.HasSuffix(strings.TrimRight(recipe, " "), "\\") && i+1 < len(lines) {
+				i++
+				recipe += "\n" + strings.TrimPrefix(lines[i], "\t")
+			}
+			for _, t := range current {
+				t.recipe = append(t.recipe, recipe)
+			}
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case trimmed == ".ONESHELL:":
+			m.oneShell = true
+			continue
+		}
+
+		if a := makeAssignment.FindStringSubmatch(trimmed); a != nil {
+			current = nil
+			name := a[1]
+			export := strings.HasPrefix(name, "export")
+			name = strings.Fields(name)[len(strings.Fields(name))-1]
+			switch {
+			case name == ".DEFAULT_GOAL":
+				m.defaultGoal = a[3]
+			case name == "SHELL":
+				m.shell = a[3]
+			case name == ".SHELLFLAGS":
+				m.shellFlags = strings.Fields(a[3])
+			case strings.HasPrefix(name, "."), a[2] == "+=":
+				// Special variables such as MAKEFLAGS tune make itself.
+			case makeArgument.MatchString(a[3]):
+				m.functions[name] = []string{a[3]}
+			default:
+				m.vars = append(m.vars, makeVar{name, a[3], export})
+			}
+			continue
+		}
+		r := makeRule.FindStringSubmatch(line)
+		if r == nil || strings.HasPrefix(trimmed, ".") {
+			current = nil
+			continue
+		}
+		colon := strings.Index(r[1], ":")
+		var prereqs []string
+		for _, p := range strings.Fields(strings.TrimLeft(r[1][colon:], ":")) {
+			if p != "|" && p != "phony" {
+				prereqs = append(prereqs, p)
+			}
+		}
+		current = nil
+		for _, name := range strings.Fields(r[1][:colon]) {
+			t := m.target(name)
+			if t == nil {
+				t = &makeTarget{name: name, file: strings.ContainsAny(name, "$/.")}
+				m.targets = append(m.targets, t)
+			}
+			t.help = r[2]
+			t.prereqs = append(t.prereqs, prereqs...)
+			current = append(current, t)
+		}
+	}
+	// The rules of a conditional only matter when they define a target,
+	// rather than order the prerequisites of one defined outside it.
+	for _, name := range conditional {
+		if m.target(name) == nil {
+			m.skip(name + " is defined within a conditional")
+		}
+	}
+	return m
+}
+
+// target returns the rule called name, or nil when there is none.
+func (m *makefileModel) target(name string) *makeTarget {
+	for _, t := range m.targets {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// variable returns the variable called name.
+func (m *makefileModel) variable(name string) (makeVar, bool) {
+	for _, v := range m.vars {
+		if v.name == name {
+			return v, true
+		}
+	}
+	return makeVar{}, false
+}
+
+// skip records something the conversion leaves out, once.
+func (m *makefileModel) skip(reason string) {
+	if !contains(m.skipped, reason) {
+		m.skipped = append(m.skipped, reason)
+	}
+}
+
+// fileTaskName returns the name of the task making the file path, such as
+// bin-golint for $(BIN)/.golint-$(GOLINT_VERSION).
+func fileTaskName(path string) string {
+	path = makeVersionSuffix.ReplaceAllString(path, "")
+	path = strings.NewReplacer("$(", "", "${", "", ")", "", "}", "").Replace(path)
+	return strings.Trim(taskName.ReplaceAllString(strings.ToLower(path), "-"), "-")
+}
+
+// makeSyntax is how a command runner writes what make expands.
+type makeSyntax struct {
+	// variable returns the reference to a variable of the Makefile.
+	variable func(name string) string
+	curdir   string
+	make     string
+	// chdir returns the arguments running the file of the runner in dir.
+	chdir func(dir string) string
+	// literal escapes text the runner would otherwise expand.
+	literal func(text string) string
+}
+
+var (
+	taskSyntax = makeSyntax{
+		variable: func(name string) string { return "{{." + name + "}}" },
+		curdir:   "{{.ROOT_DIR}}",
+		make:     "task",
+		chdir:    func(dir string) string { return " -d " + dir },
+		literal:  func(text string) string { return strings.ReplaceAll(text, "{{", `{{"{{"}}`) },
+	}
+	justSyntax = makeSyntax{
+		variable: func(name string) string { return "{{" + name + "}}" },
+		curdir:   "{{justfile_directory()}}",
+		make:     "just",
+		chdir:    func(dir string) string { return " -f " + dir + "/justfile -d " + dir },
+		literal:  func(text string) string { return strings.ReplaceAll(text, "{{", "{{{{") },
+	}
+)
+
+// expand translates the references of text to the variables of the Makefile,
+// its functions and the automatic $@ of target into the syntax s, failing for
+// the make functions the runners have no equivalent of.
+func (m *makefileModel) expand(text, target string, s makeSyntax) (string, error) {
+	var b, literal strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c != '$' || i+1 == len(text) {
+			literal.WriteByte(c)
+			continue
+		}
+		b.WriteString(s.literal(literal.String()))
+		literal.Reset()
+		next := text[i+1]
+		switch next {
+		case '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case '@':
+			// The target of a file rule is itself a path to expand.
+			expanded, err := m.expand(target, "", s)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(expanded)
+			i++
+			continue
+		case '(', '{':
+		default:
+			return "", fmt.Errorf("the automatic variable $%c", next)
+		}
+		end := matchingParen(text, i+1)
+		if end < 0 {
+			return "", fmt.Errorf("an unbalanced %s", text[i:])
+		}
+		inner := text[i+2 : end]
+		i = end
+		fields := strings.SplitN(inner, " ", 2)
+		switch {
+		case inner == "MAKE":
+			b.WriteString(s.make)
+			// make -C DIR runs the Makefile of DIR.
+			if rest := text[i+1:]; strings.HasPrefix(rest, " -C ") && len(strings.Fields(rest)) > 1 {
+				dir := strings.Fields(rest)[1]
+				expanded, err := m.expand(dir, target, s)
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(s.chdir(expanded))
+				i += len(" -C ") + len(dir)
+			}
+		case inner == "CURDIR":
+			b.WriteString(s.curdir)
+		case inner == "MAKEFILE_LIST" || inner == "MAKECMDGOALS" || inner == "MAKE_VERSION":
+			return "", fmt.Errorf("$(%s)", inner)
+		case fields[0] == "shell" && len(fields) == 2:
+			cmd, err := m.expand(fields[1], target, s)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString("$(" + cmd + ")")
+		case fields[0] == "call" && len(fields) == 2:
+			call, err := m.call(fields[1])
+			if err != nil {
+				return "", err
+			}
+			if len(call) != 1 {
+				return "", fmt.Errorf("$(call %s) outside a recipe", fields[1])
+			}
+			expanded, err := m.expand(call[0], target, s)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(expanded)
+		case strings.ContainsAny(inner, " ,:"):
+			return "", fmt.Errorf("the make function %s", fields[0])
+		default:
+			if _, ok := m.variable(inner); ok {
+				b.WriteString(s.variable(inner))
+			} else {
+				b.WriteString("${" + inner + "}")
+			}
+		}
+	}
+	b.WriteString(s.literal(literal.String()))
+	return b.String(), nil
+}
+
+// matchingParen returns the index of the parenthesis or brace closing the one
+// at open, or -1 when none does.
+func matchingParen(text string, open int) int {
+	closing := byte(')')
+	if text[open] == '{' {
+		closing = '}'
+	}
+	depth := 0
+	for i := open; i < len(text); i++ {
+		switch text[i] {
+		case text[open]:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// call returns the lines of the function a $(call) runs, with its arguments
+// substituted.
+func (m *makefileModel) call(args string) ([]string, error) {
+	parts := strings.Split(args, ",")
+	body, ok := m.functions[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("the undefined function %s", parts[0])
+	}
+	var lines []string
+	for _, line := range body {
+		lines = append(lines, makeArgument.ReplaceAllStringFunc(line, func(ref string) string {
+			n, _ := strconv.Atoi(ref[2:3])
+			if n < len(parts) {
+				return parts[n]
+			}
+			return ""
+		}))
+	}
+	return lines, nil
+}
+
+// recipe returns the commands of target in the syntax s with the $(call)s of
+// multi-line functions expanded, along with whether each one is echoed.
+func (m *makefileModel) recipe(t *makeTarget, s makeSyntax) ([]string, []bool, error) {
+	var lines []string
+	for _, line := range t.recipe {
+		trimmed := strings.TrimLeft(line, "@- ")
+		if strings.HasPrefix(trimmed, "$(call ") && strings.HasSuffix(trimmed, ")") {
+			call, err := m.call(trimmed[len("$(call ") : len(trimmed)-1])
+			if err == nil && len(call) > 1 {
+				continued := false
+				for _, l := range call {
+					l = strings.TrimPrefix(l, "\t")
+					if continued {
+						lines[len(lines)-1] += "\n" + l
+					} else {
+						lines = append(lines, l)
+					}
+					continued = strings.HasSuffix(strings.TrimRight(l, " "), "\\")
+				}
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	var commands []string
+	var echoed []bool
+	for _, line := range lines {
+		quiet := strings.HasPrefix(strings.TrimLeft(line, "-"), "@")
+		command, err := m.expand(strings.TrimLeft(line, "@-"), t.name, s)
+		if err != nil {
+			return nil, nil, err
+		}
+		commands = append(commands, command)
+		echoed = append(echoed, !quiet)
+	}
+	if m.oneShell && len(commands) > 1 {
+		commands = []string{strings.Join(commands, "\n")}
+		echoed = []bool{false}
+	}
+	return commands, echoed, nil
+}
+
+// convertible returns the targets to convert in order, the phony ones first,
+// recording those that cannot be and leaving out their dependents' references
+// to them. help and help-json are replaced by the listing of the runner.
+func (m *makefileModel) convertible(s makeSyntax) []*makeTarget {
+	var out []*makeTarget
+	for _, t := range m.targets {
+		if t.name == "help" || t.name == "help-json" {
+			continue
+		}
+		if _, _, err := m.recipe(t, s); err != nil {
+			m.skip(fmt.Sprintf("%s uses %v, which %s has no equivalent of", t.name, err, s.make))
+			continue
+		}
+		out = append(out, t)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return !out[i].file && out[j].file })
+	return out
+}
+
+// fileExpr returns the file of a file task in the syntax s.
+func (m *makefileModel) fileExpr(t *makeTarget, s makeSyntax) string {
+	path, err := m.expand(t.name, t.name, s)
+	if err != nil {
+		return t.name
+	}
+	return path
+}
+
+// taskfile returns the Makefile as a Taskfile.
+func (m *makefileModel) taskfile() ([]byte, error) {
+	s := taskSyntax
+	root := mapping()
+	root.add("version", scalar("3"))
+	var set []string
+	for i, f := range m.shellFlags {
+		switch {
+		case f == "-c":
+		case f == "-o" && i+1 < len(m.shellFlags):
+			set = append(set, m.shellFlags[i+1])
+		case strings.HasPrefix(f, "-") && f != "-o":
+			for _, c := range f[1:] {
+				switch c {
+				case 'e':
+					set = append(set, "errexit")
+				case 'u':
+					set = append(set, "nounset")
+				}
+			}
+		}
+	}
+	if len(set) > 0 {
+		root.add("set", sequence(set...))
+	}
+	root.add("silent", boolean(true))
+
+	vars, env := mapping(), mapping()
+	for _, v := range m.vars {
+		value, err := m.taskValue(v.value)
+		if err != nil {
+			m.skip(fmt.Sprintf("the variable %s uses %v, which task has no equivalent of", v.name, err))
+			continue
+		}
+		vars.add(v.name, value)
+		if v.export {
+			env.add(v.name, scalar(s.variable(v.name)))
+		}
+	}
+	if len(vars.Content) > 0 {
+		root.add("vars", vars.Node)
+	}
+	if len(env.Content) > 0 {
+		root.add("env", env.Node)
+	}
+
+	tasks := mapping()
+	goal := m.defaultGoal
+	if goal == "" || goal == "help" {
+		tasks.add("default", mapping().add("desc", scalar("list the tasks")).add("cmds", sequence("task --list")).Node)
+	} else {
+		tasks.add("default", mapping().add("cmds", sequenceOf(mapping().add("task", scalar(goal)).Node)).Node)
+	}
+	targets := m.convertible(s)
+	for _, t := range targets {
+		task := mapping()
+		name := t.name
+		if t.file {
+			name = fileTaskName(t.name)
+			task.add("internal", boolean(true))
+		} else if t.help != "" {
+			task.add("desc", scalar(t.help))
+		}
+
+		var deps, calls []*yaml.Node
+		var sources []string
+		for _, p := range t.prereqs {
+			dep := m.target(p)
+			switch {
+			case dep == nil && t.file:
+				if src, err := m.expand(p, t.name, s); err == nil {
+					sources = append(sources, src)
+				}
+			case dep == nil || !containsTarget(targets, dep):
+				continue
+			case dep.file:
+				deps = append(deps, scalar(fileTaskName(dep.name)))
+			default:
+				calls = append(calls, mapping().add("task", scalar(dep.name)).Node)
+			}
+		}
+		if len(deps) > 0 {
+			task.add("deps", sequenceOf(deps...))
+		}
+		if commands := m.needs[t.name]; len(commands) > 0 {
+			var preconditions []*yaml.Node
+			for _, c := range commands {
+				preconditions = append(preconditions, mapping().
+					add("sh", scalar("command -v "+c)).
+					add("msg", scalar(c+" is not on PATH, "+commandHint(c))).Node)
+			}
+			task.add("preconditions", sequenceOf(preconditions...))
+		}
+		if t.file {
+			if len(sources) > 0 {
+				task.add("sources", sequence(sources...))
+				task.add("generates", sequence(m.fileExpr(t, s)))
+			} else {
+				task.add("status", sequence("test -e "+m.fileExpr(t, s)))
+			}
+		}
+		commands, echoed, _ := m.recipe(t, s)
+		for i, c := range commands {
+			cmd := scalar(c)
+			if strings.Contains(c, "\n") {
+				cmd.Style = yaml.LiteralStyle
+			}
+			if echoed[i] {
+				cmd = mapping().add("cmd", cmd).add("silent", boolean(false)).Node
+			}
+			calls = append(calls, cmd)
+		}
+		if len(calls) > 0 {
+			task.add("cmds", sequenceOf(calls...))
+		}
+		tasks.add(name, task.Node)
+	}
+	tasks.add("help-json", mapping().add("desc", scalar("print the tasks as JSON")).add("cmds", sequence("task --list-all --json")).Node)
+	root.add("tasks", tasks.Node)
+
+	var b strings.Builder
+	b.WriteString("# Converted from a maker generated Makefile by maker convert.\n")
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(root.Node); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// taskValue returns the value of a variable as a Taskfile declares it,
+// computed by the shell when it is a $(shell).
+func (m *makefileModel) taskValue(value string) (*yaml.Node, error) {
+	value, err := m.inlineCalls(value)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(value, "$(shell ") && matchingParen(value, 1) == len(value)-1 {
+		cmd, err := m.expand(value[len("$(shell "):len(value)-1], "", taskSyntax)
+		if err != nil {
+			return nil, err
+		}
+		return mapping().add("sh", scalar(cmd)).Node, nil
+	}
+	expanded, err := m.expand(value, "", taskSyntax)
+	if err != nil {
+		return nil, err
+	}
+	n := scalar(expanded)
+	n.Style = yaml.DoubleQuotedStyle
+	return n, nil
+}
+
+// inlineCalls replaces a value that is a single $(call) with the function it
+// calls.
+func (m *makefileModel) inlineCalls(value string) (string, error) {
+	for strings.HasPrefix(value, "$(call ") && matchingParen(value, 1) == len(value)-1 {
+		lines, err := m.call(value[len("$(call ") : len(value)-1])
+		if err != nil {
+			return "", err
+		}
+		if len(lines) != 1 {
+			return "", fmt.Errorf("the multi-line function of %s", value)
+		}
+		value = strings.TrimSpace(lines[0])
+	}
+	return value, nil
+}
+
+// justfile returns the Makefile as a justfile.
+func (m *makefileModel) justfile() ([]byte, error) {
+	s := justSyntax
+	var b strings.Builder
+	b.WriteString("# Converted from a maker generated Makefile by maker convert.\n\n")
+	if m.shell != "" || len(m.shellFlags) > 0 {
+		shell := m.shell
+		if shell == "" {
+			shell = "sh"
+		}
+		words := []string{strconv.Quote(shell)}
+		for _, f := range m.shellFlags {
+			words = append(words, strconv.Quote(f))
+		}
+		fmt.Fprintf(&b, "set shell := [%s]\n\n", strings.Join(words, ", "))
+	}
+
+	for _, v := range m.vars {
+		value, err := m.justValue(v.value, nil)
+		if err != nil {
+			m.skip(fmt.Sprintf("the variable %s uses %v, which just has no equivalent of", v.name, err))
+			continue
+		}
+		if v.export {
+			b.WriteString("export ")
+		}
+		fmt.Fprintf(&b, "%s := %s\n", v.name, value)
+	}
+	if len(m.vars) > 0 {
+		b.WriteString("\n")
+	}
+
+	goal := m.defaultGoal
+	if goal == "" || goal == "help" {
+		b.WriteString("# list the recipes\ndefault:\n    @just --list\n")
+	} else {
+		fmt.Fprintf(&b, "default: %s\n", goal)
+	}
+	targets := m.convertible(s)
+	for _, t := range targets {
+		b.WriteString("\n")
+		name := t.name
+		if t.file {
+			name = "_" + fileTaskName(t.name)
+		} else if t.help != "" {
+			fmt.Fprintf(&b, "# %s\n", t.help)
+		}
+		b.WriteString(name + ":")
+		for _, p := range t.prereqs {
+			dep := m.target(p)
+			switch {
+			case dep == nil || !containsTarget(targets, dep):
+			case dep.file:
+				b.WriteString(" _" + fileTaskName(dep.name))
+			default:
+				b.WriteString(" " + dep.name)
+			}
+		}
+		b.WriteString("\n")
+		commands, echoed, _ := m.recipe(t, s)
+		if m.oneShell && len(commands) == 1 {
+			fmt.Fprintf(&b, "    #!/usr/bin/env %s\n", strings.TrimPrefix(firstNonEmpty(m.shell, "sh"), "/bin/"))
+			if flags := strings.TrimSpace(strings.TrimSuffix(strings.Join(m.shellFlags, " "), "-c")); flags != "" {
+				fmt.Fprintf(&b, "    set %s\n", flags)
+			}
+			echoed[0] = true
+		}
+		for i, c := range commands {
+			if t.file {
+				c = fmt.Sprintf("[ -e \"%s\" ] || { %s; }", m.fileExpr(t, s), c)
+			}
+			prefix := "@"
+			if echoed[i] {
+				prefix = ""
+			}
+			lines := strings.Split(c, "\n")
+			fmt.Fprintf(&b, "    %s%s\n", prefix, lines[0])
+			for _, l := range lines[1:] {
+				fmt.Fprintf(&b, "    %s\n", strings.ReplaceAll(l, "\t", "    "))
+			}
+		}
+	}
+	b.WriteString("\n# print the recipes as JSON\nhelp-json:\n    @just --dump --dump-format json\n")
+	return []byte(b.String()), nil
+}
+
+// justValue returns the value of a variable as a justfile expression, a
+// backtick when it is a $(shell). A backtick cannot refer to the variables of
+// the justfile, so those it uses are inlined, seen guarding against cycles.
+func (m *makefileModel) justValue(value string, seen []string) (string, error) {
+	value, err := m.inlineCalls(value)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(value, "$(shell ") && matchingParen(value, 1) == len(value)-1 {
+		cmd, err := m.shellValue(value[len("$(shell "):len(value)-1], seen)
+		if err != nil {
+			return "", err
+		}
+		return "`" + cmd + "`", nil
+	}
+
+	// Anything else is a concatenation of strings, variables and the
+	// directory of the justfile.
+	var parts []string
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, strconv.Quote(literal.String()))
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			literal.WriteByte(value[i])
+			continue
+		}
+		if value[i+1] == '$' {
+			literal.WriteByte('$')
+			i++
+			continue
+		}
+		end := -1
+		if value[i+1] == '(' || value[i+1] == '{' {
+			end = matchingParen(value, i+1)
+		}
+		if end < 0 {
+			return "", fmt.Errorf("the automatic variable %s", value[i:i+2])
+		}
+		inner := value[i+2 : end]
+		i = end
+		flush()
+		if inner == "CURDIR" {
+			parts = append(parts, "justfile_directory()")
+		} else if _, ok := m.variable(inner); ok {
+			parts = append(parts, inner)
+		} else {
+			return "", fmt.Errorf("$(%s)", inner)
+		}
+	}
+	flush()
+	if len(parts) == 0 {
+		return `""`, nil
+	}
+	return strings.Join(parts, " + "), nil
+}
+
+// shellValue returns the command of a $(shell) as a backtick runs it, in the
+// directory of the justfile, with the variables it uses inlined. Within single
+// quotes, the expansions replacing them close the quotes around themselves.
+func (m *makefileModel) shellValue(cmd string, seen []string) (string, error) {
+	var b strings.Builder
+	quoted := false
+	insert := func(expansion string) {
+		if quoted {
+			expansion = `'"` + expansion + `"'`
+		}
+		b.WriteString(expansion)
+	}
+	for i := 0; i < len(cmd); i++ {
+		if cmd[i] == '\'' {
+			quoted = !quoted
+		}
+		if cmd[i] != '$' || i+1 == len(cmd) {
+			b.WriteByte(cmd[i])
+			continue
+		}
+		if cmd[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		end := -1
+		if cmd[i+1] == '(' || cmd[i+1] == '{' {
+			end = matchingParen(cmd, i+1)
+		}
+		if end < 0 {
+			return "", fmt.Errorf("the automatic variable %s", cmd[i:i+2])
+		}
+		inner := cmd[i+2 : end]
+		i = end
+		v, ok := m.variable(inner)
+		switch {
+		case inner == "CURDIR":
+			insert("$PWD")
+			continue
+		case !ok:
+			return "", fmt.Errorf("$(%s)", inner)
+		case contains(seen, inner):
+			return "", fmt.Errorf("the recursive variable %s", inner)
+		}
+		value, err := m.inlineCalls(v.value)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(value, "$(shell ") && matchingParen(value, 1) == len(value)-1 {
+			inlined, err := m.shellValue(value[len("$(shell "):len(value)-1], append(seen, inner))
+			if err != nil {
+				return "", err
+			}
+			insert("$(" + inlined + ")")
+			continue
+		}
+		inlined, err := m.shellValue(value, append(seen, inner))
+		if err != nil {
+			return "", err
+		}
+		if quoted && strings.Contains(inlined, "'") {
+			return "", fmt.Errorf("the quoted variable %s", inner)
+		}
+		b.WriteString(inlined)
+	}
+	return b.String(), nil
+}
+
+// containsTarget reports whether targets has t.
+func containsTarget(targets []*makeTarget, t *makeTarget) bool {
+	for _, other := range targets {
+		if other == t {
+			return true
+		}
+	}
+	return false
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// yamlMapping builds a YAML mapping node keeping the order its keys are
+// added in.
+type yamlMapping struct {
+	*yaml.Node
+}
+
+func mapping() yamlMapping {
+	return yamlMapping{&yaml.Node{Kind: yaml.MappingNode}}
+}
+
+func (m yamlMapping) add(key string, value *yaml.Node) yamlMapping {
+	m.Content = append(m.Content, scalar(key), value)
+	return m
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func boolean(value bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}
+}
+
+func sequence(values ...string) *yaml.Node {
+	var nodes []*yaml.Node
+	for _, v := range values {
+		nodes = append(nodes, scalar(v))
+	}
+	return sequenceOf(nodes...)
+}
+
+func sequenceOf(nodes ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Content: nodes}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	const makefile = `GO := go
+NAME := app
+BIN = bin/$(NAME)
+
+.PHONY: build test
+build: ## Builds the binary
+	$(GO) build -o $(BIN) .
+
+test: build ## Runs the tests
+	@$(GO) test ./...
+`
+	m := parseMakefile([]byte(makefile))
+	for _, c := range []struct {
+		format string
+		render func() ([]byte, error)
+		want   []string
+	}{
+		{"taskfile", m.taskfile, []string{
+			"  GO: \"go\"\n",
+			"  BIN: \"bin/{{.NAME}}\"\n",
+			"  build:\n    desc: Builds the binary\n    cmds:\n      - cmd: '{{.GO}} build -o {{.BIN}} .'\n        silent: false\n",
+			"  test:\n    desc: Runs the tests\n    cmds:\n      - task: build\n      - '{{.GO}} test ./...'\n",
+		}},
+		{"justfile", m.justfile, []string{
+			"GO := \"go\"\n",
+			"BIN := \"bin/\" + NAME\n",
+			"# Builds the binary\nbuild:\n    {{GO}} build -o {{BIN}} .\n",
+			"# Runs the tests\ntest: build\n    @{{GO}} test ./...\n",
+		}},
+	} {
+		t.Run(c.format, func(t *testing.T) {
+			out, err := c.render()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range c.want {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("the %s lacks\n%s\nin\n%s", c.format, want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMakefile(t *testing.T) {
+	m := parseMakefile([]byte(`.DEFAULT_GOAL := build
+SHELL := bash
+export CGO_ENABLED ?= 0
+
+ifeq ($(CI),1)
+FLAGS := -v
+endif
+
+bin/tool:
+	touch $@
+
+build: bin/tool ## Builds
+	go build
+`))
+	if m.defaultGoal != "build" || m.shell != "bash" {
+		t.Errorf("default goal %q and shell %q, want build and bash", m.defaultGoal, m.shell)
+	}
+	if v, ok := m.variable("CGO_ENABLED"); !ok || v.value != "0" || !v.export {
+		t.Errorf("CGO_ENABLED = %+v, %v, want an exported 0", v, ok)
+	}
+	if _, ok := m.variable("FLAGS"); ok {
+		t.Error("the variable of a conditional was kept")
+	}
+	for _, c := range []struct {
+		name, help string
+		prereqs    []string
+		file       bool
+	}{
+		{"bin/tool", "", nil, true},
+		{"build", "Builds", []string{"bin/tool"}, false},
+	} {
+		target := m.target(c.name)
+		if target == nil {
+			t.Errorf("no target %s", c.name)
+			continue
+		}
+		if target.help != c.help || strings.Join(target.prereqs, " ") != strings.Join(c.prereqs, " ") || target.file != c.file {
+			t.Errorf("target %s = %+v, want help %q, prerequisites %q and file %v", c.name, *target, c.help, c.prereqs, c.file)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// toolsFile pins the versions of the tools the generated Makefile installs
+// into bin.
+const toolsFile = "tools.yaml"
+
+// finding is the outcome of a doctor check. A failed finding explains how to
+// fix it.
+type finding struct {
+	ok   bool
+	text string
+}
+
+// doctor checks the environment of a generated project against what its lock
+// file says it was generated with, and prints how to fix what is missing.
+func doctor(args []string) {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	flags.BoolVar(&plain, "plain", false, "Prints the checks without colors or glyphs")
+	flags.Parse(args)
+
+	if len(flags.Args()) > 1 {
+		fmt.Println("Expected use: maker doctor [-plain] [DIR]")
+		os.Exit(1)
+	}
+	dir := "."
+	if len(flags.Args()) == 1 {
+		dir = flags.Arg(0)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	generated, err := readLock(filepath.Join(dir, lockFile))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	tools, err := checkTools(dir)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	findings := []finding{checkGo(ctx, dir), checkMake(ctx, dir)}
+	if generated.enabled("docker") || generated.enabled("lintDocker") || exists(filepath.Join(dir, "Dockerfile")) {
+		findings = append(findings, checkDocker(ctx))
+	}
+	findings = append(findings, tools...)
+	findings = append(findings, checkRequirements(dir)...)
+	findings = append(findings, checkPath(ctx), checkGit(ctx, dir))
+
+	healthy := true
+	for _, f := range findings {
+		if f.ok {
+			report(passed, f.text)
+		} else {
+			report(failed, f.text)
+			healthy = false
+		}
+	}
+	if !healthy {
+		os.Exit(1)
+	}
+}
+
+// checkGo checks that go is installed and at least as new as the go directive
+// of the go.mod file in dir.
+func checkGo(ctx context.Context, dir string) finding {
+	out, err := queryCommand(ctx, "", "go", "env", "GOVERSION")
+	if err != nil {
+		return finding{false, "go is not installed, install it from https://go.dev/dl/"}
+	}
+	version := strings.TrimSpace(out)
+	want, err := goDirective(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return finding{false, err.Error()}
+	}
+	if want != "" && compareVersions(strings.TrimPrefix(version, "go"), want) < 0 {
+		return finding{false, fmt.Sprintf("%s is older than go %s required by go.mod, install a newer go from https://go.dev/dl/", version, want)}
+	}
+	return finding{true, version}
+}
+
+// goDirective returns the version of the go directive in the go.mod file at
+// path, or an empty string when there is none.
+func goDirective(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(contents), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "go" {
+			return fields[1], nil
+		}
+	}
+	return "", nil
+}
+
+// makeMinVersion matches the minimum GNU Make version set by -make-features.
+var makeMinVersion = regexp.MustCompile(`(?m)^MAKE_MIN_VERSION\s*:?=\s*(\S+)`)
+
+// checkMake checks that make is GNU Make, and at least the MAKE_MIN_VERSION
+// of the Makefile in dir when it sets one.
+func checkMake(ctx context.Context, dir string) finding {
+	out, err := queryCommand(ctx, "", "make", "--version")
+	if err != nil {
+		return finding{false, "make is not installed, install GNU Make"}
+	}
+	line := strings.SplitN(out, "\n", 2)[0]
+	if !strings.HasPrefix(line, "GNU Make ") {
+		return finding{false, fmt.Sprintf("%s is not GNU Make, install GNU Make and run it as gmake or make", line)}
+	}
+	contents, _ := ioutil.ReadFile(filepath.Join(dir, "Makefile"))
+	if m := makeMinVersion.FindSubmatch(contents); m != nil {
+		if version := strings.TrimPrefix(line, "GNU Make "); compareVersions(version, string(m[1])) < 0 {
+			return finding{false, fmt.Sprintf("%s is older than %s required by the Makefile, install a newer GNU Make", line, m[1])}
+		}
+	}
+	return finding{true, line}
+}
+
+// checkDocker checks that docker is installed and its daemon is reachable.
+func checkDocker(ctx context.Context) finding {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return finding{false, "docker is not installed, install it from https://docs.docker.com/get-docker/"}
+	}
+	out, err := queryCommand(ctx, "", "docker", "version", "--format", "{{.Server.Version}}")
+	if err != nil {
+		return finding{false, "docker cannot reach its daemon, start Docker"}
+	}
+	return finding{true, "docker " + strings.TrimSpace(out)}
+}
+
+// checkTools returns a finding for each tool pinned in the tools file of dir,
+// failing those that bin holds no sentinel of, since the Makefile touches
+// bin/.NAME-VERSION on installing a tool. A project without a tools file has
+// no findings.
+func checkTools(dir string) ([]finding, error) {
+	path := filepath.Join(dir, toolsFile)
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tools map[string]string
+	if err := yaml.Unmarshal(contents, &tools); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	var names []string
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var findings []finding
+	for _, name := range names {
+		tool := name + " " + tools[name]
+		if exists(filepath.Join(dir, "bin", "."+name+"-"+tools[name])) {
+			findings = append(findings, finding{true, tool})
+		} else {
+			findings = append(findings, finding{false, tool + " is not installed, run make bootstrap"})
+		}
+	}
+	return findings, nil
+}
+
+// checkPath checks that the directory go install writes to is on PATH, so
+// that tools installed outside the project, maker included, can be run.
+func checkPath(ctx context.Context) finding {
+	out, err := queryCommand(ctx, "", "go", "env", "GOBIN", "GOPATH")
+	if err != nil {
+		return finding{false, "go is not installed, install it from https://go.dev/dl/"}
+	}
+	env := strings.Split(out, "\n")
+	bin := strings.TrimSpace(env[0])
+	if bin == "" && len(env) > 1 {
+		bin = filepath.Join(strings.TrimSpace(env[1]), "bin")
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(dir) == filepath.Clean(bin) {
+			return finding{true, bin + " is on PATH"}
+		}
+	}
+	return finding{false, fmt.Sprintf("%s is not on PATH, add it to run tools installed with go install", bin)}
+}
+
+// checkGit checks that dir is in a git repository with a remote.
+func checkGit(ctx context.Context, dir string) finding {
+	if _, err := queryCommand(ctx, dir, "git", "rev-parse", "--git-dir"); err != nil {
+		return finding{false, "not a git repository, run git init"}
+	}
+	out, err := queryCommand(ctx, dir, "git", "remote")
+	if err != nil || strings.TrimSpace(out) == "" {
+		return finding{false, "the git repository has no remote, run git remote add origin URL"}
+	}
+	return finding{true, "git remote " + strings.Fields(out)[0]}
+}
+
+// compareVersions compares the dotted versions a and b numerically, ignoring
+// any suffix after the numbers, and returns -1, 0 or 1.
+func compareVersions(a, b string) int {
+	as, bs := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionNumbers returns the leading numbers of a dotted version.
+func versionNumbers(version string) []int {
+	var numbers []int
+	for _, part := range strings.Split(version, ".") {
+		digits := part
+		if i := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+			digits = part[:i]
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, n)
+		if digits != part {
+			break
+		}
+	}
+	return numbers
+}
+
+// exists reports whether a file exists at path.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
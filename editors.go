@@ -0,0 +1,95 @@
+package main
+
+// jetbrainsTargets are the names of every task, each of which has a JetBrains
+// run configuration template.
+var jetbrainsTargets = []string{"generate", "build", "run", "fmt", "lint", "vet", "test", "bench", "test-cover", "test-race"}
+
+// The templates of the editor integration files.
+func init() {
+	for _, target := range jetbrainsTargets {
+		fileTemplates["jetbrains/"+target+".run.xml"] = `<component name="ProjectRunConfigurationManager">
+  <configuration default="false" name="make ` + target + `" type="MAKEFILE_TARGET_RUN_CONFIGURATION" factoryName="Makefile">
+    <makefile filename="$PROJECT_DIR$/Makefile" target="` + target + `" workingDirectory="$PROJECT_DIR$" arguments="">
+      <envs />
+    </makefile>
+    <method v="2" />
+  </configuration>
+</component>
+`
+	}
+
+	fileTemplates["jetbrains/attach.run.xml"] = `<component name="ProjectRunConfigurationManager">
+  <configuration default="false" name="attach to make debug" type="GoRemoteDebugConfigurationType" factoryName="Go Remote">
+    <option name="host" value="127.0.0.1" />
+    <option name="port" value="2345" />
+    <option name="disconnectOption" value="ASK" />
+    <method v="2" />
+  </configuration>
+</component>
+`
+
+	fileTemplates["vscode/tasks.json"] = `{
+  "version": "2.0.0",
+  "tasks": [
+{{- range $i, $t := tasks .}}
+{{- if $i}},{{end}}
+    {
+      "label": "make {{$t.Name}}",
+      "detail": "{{$t.Description}}",
+      "type": "shell",
+      "command": "make {{$t.Name}}",
+{{- if eq $t.Name "build"}}
+      "group": {
+        "kind": "build",
+        "isDefault": true
+      },
+{{- else if eq $t.Name "test"}}
+      "group": {
+        "kind": "test",
+        "isDefault": true
+      },
+{{- end}}
+      "problemMatcher": [
+        "$go"
+      ]
+    }
+{{- end}}
+  ]
+}
+`
+
+	fileTemplates["vscode/launch.json"] = `{
+  "version": "0.2.0",
+  "configurations": [
+{{- if not .library}}
+{{- range .meta.BinaryNames}}
+    {
+      "name": "Launch {{.}}",
+      "type": "go",
+      "request": "launch",
+      "mode": "debug",
+      "program": "${workspaceFolder}{{if $.meta.Binaries}}/cmd/{{.}}{{end}}"
+    },
+{{- end}}
+{{- end}}
+{{- if .debug}}
+    {
+      "name": "Attach to make debug",
+      "type": "go",
+      "request": "attach",
+      "mode": "remote",
+      "host": "127.0.0.1",
+      "port": 2345
+    },
+{{- end}}
+    {
+      "name": "Test package",
+      "type": "go",
+      "request": "launch",
+      "mode": "test",
+      "program": "${fileDirname}"
+    }
+  ]
+}
+`
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// feature is an option of the generated project. Enabling it sets its name in
+// the template data, adds its files to the project and pins its tools in the
+// tools file. Its Makefile targets are the sections of makefileTemplate that
+// are conditioned on it.
+type feature struct {
+	name  string
+	usage string
+	// files are rendered into every project but a monorepo. Their paths are
+	// templates too.
+	files []file
+	tools []tool
+	// targets are the Makefile targets it adds, by which maker adopt
+	// recognizes it.
+	targets []string
+	// commands are the commands its targets run that are not installed
+	// with the tools.
+	commands []string
+	// requires are the features this one needs to be enabled too, and
+	// conflicts the ones it cannot be enabled with.
+	requires  []string
+	conflicts []string
+	// cgo is set for features that need cgo.
+	cgo bool
+	// experimental is set for features whose targets may still change. They
+	// only run with EXPERIMENTAL=1 and help labels them.
+	experimental bool
+	// ciSteps are the targets the CI pipeline runs, in whichever CI config
+	// the project uses.
+	ciSteps []ciStep
+}
+
+// tool is a tool the Makefile installs into bin, either a Go package installed
+// at a version or a docker image pulled at a tag.
+type tool struct {
+	name    string
+	pkg     string
+	image   string
+	version string
+}
+
+// baseTools are installed into every project that has a tools file.
+var baseTools = []tool{
+	{name: "golint", pkg: "golang.org/x/lint/golint", version: "v0.0.0-20210508222113-6edffad5e616"},
+}
+
+// baseCommands are run by the targets of every project.
+var baseCommands = []string{"go", "git", "awk"}
+
+// features are the features maker can generate, in the order their files and
+// tools are added to a project.
+var features = []feature{
+	{name: "test", usage: "Adds test to makefile", targets: []string{"test", "test-offline"}, files: []file{
+		{"golden_test.go", "golden_test.go", 0644},
+		{"{{if .library}}{{.name}}{{else}}main{{end}}_test.go", "starter_test.go", 0644},
+		{"testdata/TestName/name.golden", "testdata/TestName/name.golden", 0644},
+	}, ciSteps: []ciStep{{stage: "test", target: "test"}}},
+	{name: "bench", usage: "Adds bench to makefile", targets: []string{"bench"}, files: []file{
+		{"bench_test.go", "bench_test.go", 0644},
+	}},
+	{name: "fuzz", usage: "Adds fuzz and fuzz corpus management to makefile", targets: []string{"fuzz", "fuzz-corpus", "fuzz-crashers", "fuzz-clean"}},
+	{name: "mutation", usage: "Adds mutation testing with gremlins to makefile", targets: []string{"mutate"}, experimental: true, tools: []tool{
+		{name: "gremlins", pkg: "github.com/go-gremlins/gremlins/cmd/gremlins", version: "v0.5.0"},
+	}},
+	{name: "licenseCheck", usage: "Adds dependency license checks with go-licenses to makefile", targets: []string{"licenses", "licenses-report"}, files: []file{
+		{licensesAllowFile, licensesAllowFile, 0644},
+	}, tools: []tool{
+		{name: "go-licenses", pkg: "github.com/google/go-licenses", version: "v1.6.0"},
+	}, ciSteps: []ciStep{{stage: "check", target: "licenses"}}},
+	{name: "depsGraph", usage: "Adds a Mermaid module dependency graph to makefile", targets: []string{"deps-graph"}},
+	{name: "deadcode", usage: "Adds dead code and unused symbol detection to makefile", targets: []string{"deadcode"}, tools: []tool{
+		{name: "deadcode", pkg: "golang.org/x/tools/cmd/deadcode", version: "v0.21.0"},
+		{name: "staticcheck", pkg: "honnef.co/go/tools/cmd/staticcheck", version: "2023.1.7"},
+	}, ciSteps: []ciStep{{stage: "check", target: "deadcode"}}},
+	{name: "spellcheck", usage: "Adds spell checking of Go and Markdown files with misspell to makefile", targets: []string{"spellcheck"}, files: []file{
+		{"spellcheck.ignore", "spellcheck.ignore", 0644},
+	}, tools: []tool{
+		{name: "misspell", pkg: "github.com/golangci/misspell/cmd/misspell", version: "v0.6.0"},
+	}, ciSteps: []ciStep{{stage: "check", target: "spellcheck"}}},
+	{name: "secretsScan", usage: "Adds secrets-scan with gitleaks and a baseline of accepted findings to makefile", targets: []string{"secrets-scan", "secrets-baseline"}, files: []file{
+		{".gitleaks.toml", ".gitleaks.toml", 0644},
+		{".gitleaks-baseline.json", ".gitleaks-baseline.json", 0644},
+	}, tools: []tool{
+		{name: "gitleaks", pkg: "github.com/zricethezav/gitleaks/v8", version: "v8.18.4"},
+	}, ciSteps: []ciStep{{stage: "check", target: "secrets-scan"}}},
+	{name: "secretsHook", usage: "Adds a pre-push hook running secrets-scan and install-hooks to makefile", targets: []string{"install-hooks"}, files: []file{
+		{".githooks/pre-push", "pre-push", 0755},
+	}, requires: []string{"secretsScan"}},
+	{name: "shadow", usage: "Adds shadow to makefile", tools: []tool{
+		{name: "shadow", pkg: "golang.org/x/tools/go/analysis/passes/shadow/cmd/shadow", version: "v0.21.0"},
+	}},
+	{name: "cover", usage: "Adds cover to makefile", targets: []string{"test-cover"}, requires: []string{"test"}, ciSteps: []ciStep{
+		{stage: "test", target: "test-cover", artifact: "bin/cover.out"},
+	}},
+	{name: "coverHTML", usage: "Adds cover HTML to makefile", targets: []string{"test-cover-html", "cover-serve"}, requires: []string{"test"}, commands: []string{"python3"}},
+	{name: "cpuProfile", usage: "Adds CPU profiling to makefile", targets: []string{"test-cpu"}},
+	{name: "memProfile", usage: "Adds Memory profiling to makefile", targets: []string{"test-mem"}},
+	{name: "race", usage: "Adds race checking to makefile", targets: []string{"build-race"}, cgo: true},
+	{name: "testRace", usage: "Adds race checking tests to makefile", targets: []string{"test-race"}, cgo: true, ciSteps: []ciStep{
+		{stage: "test", target: "test-race"},
+	}},
+	{name: "parallelTests", usage: "Runs the subtests of the generated tests in parallel", requires: []string{"test"}},
+	{name: "lintDocker", usage: "Adds dockerized golangci-lint to makefile", targets: []string{"lint-docker"}, tools: []tool{
+		{name: "golangci-lint", image: "golangci/golangci-lint", version: "v2.1.6"},
+	}, commands: []string{"docker"}},
+	{name: "cache", usage: "Adds project-local GOCACHE, GOMODCACHE and GOLANGCI_LINT_CACHE to makefile"},
+	{name: "docker", usage: "Creates a Dockerfile and adds docker-build to makefile", targets: []string{"docker-build"}, conflicts: []string{"library"}, commands: []string{"docker"}},
+	{name: "imageScan", usage: "Adds image-scan checking the docker image for vulnerabilities with trivy to makefile and the release workflow", targets: []string{"image-scan"}, files: []file{
+		{".trivyignore", ".trivyignore", 0644},
+	}, tools: []tool{
+		{name: "trivy", image: "aquasec/trivy", version: "0.56.2"},
+	}, requires: []string{"docker"}, commands: []string{"docker"}},
+	{name: "release", usage: "Adds dist, tag and release-notes to makefile and a GitHub release workflow with provenance", targets: []string{"dist", "tag", "release-notes"}, files: []file{
+		{".github/workflows/release.yml", "release.yml", 0644},
+	}, conflicts: []string{"library"}, commands: []string{"sha256sum"}},
+	{name: "goreleaser", usage: "Creates a goreleaser config releasing each binary and adds goreleaser and release-BINARY to makefile", targets: []string{"goreleaser"}, files: []file{
+		{".goreleaser.yaml", ".goreleaser.yaml", 0644},
+	}, tools: []tool{
+		{name: "goreleaser", pkg: "github.com/goreleaser/goreleaser/v2", version: "v2.8.2"},
+	}, conflicts: []string{"library"}},
+	{name: "systemd", usage: "Creates a systemd unit and adds install-service and uninstall-service to makefile", targets: []string{"install-service", "uninstall-service"}, files: []file{
+		{"deploy/{{.name}}.service", "systemd.service", 0644},
+	}, conflicts: []string{"library"}, commands: []string{"systemctl", "install", "sed"}},
+	{name: "ci", usage: "Creates a CI config for the -ci-provider running the pipeline against the -go-versions matrix", files: []file{
+		{"{{.pipeline.Provider.File}}", "{{.pipeline.Provider.Template}}", 0644},
+	}},
+	{name: "buildkitCache", usage: "Uses BuildKit cache mounts for modules and the build cache in the Dockerfile", requires: []string{"docker"}},
+	{name: "debug", usage: "Adds debug and debug-test running the binary and tests in a headless dlv to makefile", targets: []string{"debug", "debug-test"}, tools: []tool{
+		{name: "dlv", pkg: "github.com/go-delve/delve/cmd/dlv", version: "v1.23.1"},
+	}, conflicts: []string{"library"}},
+	{name: "vscode", usage: "Creates VS Code tasks running the make targets and launch configurations debugging with delve", files: []file{
+		{".vscode/tasks.json", "vscode/tasks.json", 0644},
+		{".vscode/launch.json", "vscode/launch.json", 0644},
+	}},
+	{name: "jetbrains", usage: "Creates JetBrains run configurations running the make targets"},
+	{name: "adr", usage: "Creates docs/adr with a record template and a first record, for maker adr new to add to", files: []file{
+		{adrDir + "/" + adrTemplateFile, "adr/" + adrTemplateFile, 0644},
+		{adrDir + "/0001-use-maker-generated-build-tooling.md", "adr/0001-use-maker-generated-build-tooling.md", 0644},
+	}},
+	{name: "library", usage: "Creates a library makefile", targets: []string{"apidiff"}, files: []file{
+		{"{{.name}}.go", "library.go", 0744},
+		{"example_test.go", "example_test.go", 0644},
+	}, tools: []tool{
+		{name: "gorelease", pkg: "golang.org/x/exp/cmd/gorelease", version: "v0.0.0-20240325151524-a685a6edb6d8"},
+	}},
+}
+
+// isOption reports whether name is one of the features.
+func isOption(name string) bool {
+	_, ok := lookupFeature(name)
+	return ok
+}
+
+// lookupFeature returns the feature called name.
+func lookupFeature(name string) (feature, bool) {
+	for _, f := range features {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return feature{}, false
+}
+
+// experimentalTargets returns the targets of the enabled experimental
+// features.
+func experimentalTargets(data map[string]interface{}) []string {
+	var targets []string
+	for _, f := range enabledFeatures(data) {
+		if f.experimental {
+			targets = append(targets, f.targets...)
+		}
+	}
+	return targets
+}
+
+// enabledFeatures returns the features enabled in the template data.
+func enabledFeatures(data map[string]interface{}) []feature {
+	var enabled []feature
+	for _, f := range features {
+		if data[f.name] == true {
+			enabled = append(enabled, f)
+		}
+	}
+	return enabled
+}
+
+// enableRequired enables the features the enabled ones require, and those
+// these require in turn, and returns each it enabled with the feature needing
+// it. A feature the config disables stays disabled for checkFeatures to
+// explain.
+func enableRequired(data, config map[string]interface{}) []string {
+	var enabled []string
+	for changed := true; changed; {
+		changed = false
+		for _, f := range enabledFeatures(data) {
+			for _, name := range f.requires {
+				if data[name] == true || config[name] == false {
+					continue
+				}
+				data[name] = true
+				enabled = append(enabled, fmt.Sprintf("%s, which %s requires", name, f.name))
+				changed = true
+			}
+		}
+	}
+	return enabled
+}
+
+// checkFeatures explains the enabled features that would render broken or
+// missing targets: those missing a feature they require, those enabled with
+// one they conflict with and those needing cgo when it is disabled.
+func checkFeatures(ctx context.Context, data map[string]interface{}) error {
+	var problems []string
+	for _, f := range enabledFeatures(data) {
+		for _, name := range f.requires {
+			if data[name] != true {
+				problems = append(problems, fmt.Sprintf("%s requires %s, enable it with -%s", f.name, name, name))
+			}
+		}
+		for _, name := range f.conflicts {
+			if data[name] == true {
+				problems = append(problems, fmt.Sprintf("%s conflicts with %s, disable one of them", f.name, name))
+			}
+		}
+		if f.cgo && !cgoEnabled(ctx) {
+			problems = append(problems, fmt.Sprintf("%s requires cgo, which CGO_ENABLED=0 disables", f.name))
+		}
+	}
+	if data["release"] == true && data["module"] == "" {
+		problems = append(problems, "release requires a go.mod for the workflow to read the go version from, set -mod or -modulePrefix")
+	}
+	if data["goreleaser"] == true && data["module"] == "" {
+		problems = append(problems, "goreleaser requires a go.mod to build the binaries with, set -mod or -modulePrefix")
+	}
+	if data["ci"] == true && data["module"] == "" {
+		problems = append(problems, "ci requires a go.mod for the minimum go version, set -mod or -modulePrefix")
+	}
+	if repo, _ := data["vcs"].(vcsRepo); data["release"] == true && repo.Host.Name != "github" {
+		problems = append(problems, fmt.Sprintf("release publishes with the GitHub CLI, which cannot release to %s", repo.Host.Name))
+	}
+	if p, _ := data["pipeline"].(pipeline); data["ci"] == true && p.Provider.containers {
+		for _, v := range strings.Split(data["goVersions"].(string), ",") {
+			if v == "oldstable" || v == "tip" {
+				problems = append(problems, fmt.Sprintf("ci on %s runs in golang images, which have no %s tag, list release numbers in -go-versions", p.Provider.Name, v))
+			}
+		}
+	}
+	if data["systemd"] == true && data["type"] != "http" {
+		problems = append(problems, fmt.Sprintf("systemd requires the http type, which runs as a daemon, not %s", data["type"]))
+	}
+	if data["format"] != "" && data["type"] == "monorepo" {
+		problems = append(problems, fmt.Sprintf("format %s conflicts with the monorepo type, add it to a service instead", data["format"]))
+	}
+	if data["lang"] != "go" && data["type"] == "monorepo" {
+		problems = append(problems, fmt.Sprintf("lang %s conflicts with the monorepo type, add it to a service instead", data["lang"]))
+	}
+	if data["docker"] == true && data["type"] == "monorepo" {
+		problems = append(problems, "docker conflicts with the monorepo type, add it to a service instead")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid options:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// cgoEnabled reports whether the go command builds with cgo. It counts as
+// enabled when go cannot be run, leaving that to maker doctor.
+func cgoEnabled(ctx context.Context) bool {
+	out, err := queryCommand(ctx, "", "go", "env", "CGO_ENABLED")
+	return err != nil || strings.TrimSpace(out) != "0"
+}
+
+// enabledCommands returns the commands the targets of the project run, for
+// makefile-test to check they are on PATH.
+func enabledCommands(data map[string]interface{}) []string {
+	commands := append([]string{}, baseCommands...)
+	for _, f := range enabledFeatures(data) {
+		commands = append(commands, f.commands...)
+	}
+	switch data["lang"] {
+	case "go+node":
+		commands = append(commands, "npm")
+	case "go+python":
+		commands = append(commands, "python3")
+	}
+
+	var unique []string
+	for _, c := range commands {
+		if !contains(unique, c) {
+			unique = append(unique, c)
+		}
+	}
+	return unique
+}
+
+// enabledTools returns the base tools and the tools of the enabled features
+// for the templates, with the Makefile variable holding each version.
+func enabledTools(data map[string]interface{}) []map[string]string {
+	tools := append([]tool{}, baseTools...)
+	for _, f := range enabledFeatures(data) {
+		tools = append(tools, f.tools...)
+	}
+
+	var out []map[string]string
+	for _, t := range tools {
+		out = append(out, map[string]string{
+			"name":     t.name,
+			"package":  t.pkg,
+			"image":    t.image,
+			"version":  t.version,
+			"variable": strings.ToUpper(strings.ReplaceAll(t.name, "-", "_")) + "_VERSION",
+		})
+	}
+	return out
+}
+
+// list prints the features with the files and tools they add.
+func list(args []string) {
+	if len(args) != 0 {
+		fmt.Println("Expected use: maker list")
+		os.Exit(1)
+	}
+	for _, f := range features {
+		fmt.Printf("%-16s %s\n", f.name, f.usage)
+		if f.experimental {
+			fmt.Printf("%-16s   experimental, its targets run with EXPERIMENTAL=1\n", "")
+		}
+		for _, file := range f.files {
+			fmt.Printf("%-16s   file %s\n", "", file.path)
+		}
+		for _, t := range f.tools {
+			fmt.Printf("%-16s   tool %s %s\n", "", t.name, t.version)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// force regenerates a project into an existing directory.
+var force bool
+
+// dryRun reports the files a project would be generated with without writing
+// them.
+var dryRun bool
+
+// fileSystem is what a project is generated into.
+type fileSystem interface {
+	// WriteFile writes contents to the slash separated path, relative to
+	// the project directory.
+	WriteFile(path string, contents []byte, perm os.FileMode) error
+}
+
+// memFile is a file held in memory until it is committed.
+type memFile struct {
+	contents []byte
+	perm     os.FileMode
+}
+
+// memFS is the in-memory filesystem a project is generated into, so that a
+// project is either written whole or not at all.
+type memFS struct {
+	paths []string
+	files map[string]memFile
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: map[string]memFile{}}
+}
+
+// WriteFile holds contents at path until the files are committed.
+func (m *memFS) WriteFile(path string, contents []byte, perm os.FileMode) error {
+	if _, ok := m.files[path]; !ok {
+		m.paths = append(m.paths, path)
+	}
+	m.files[path] = memFile{contents, perm}
+	return nil
+}
+
+// commit writes the files into the new directory dir. They are written into a
+// temporary sibling of dir first, which is renamed to dir once every file is
+// written, so a failure leaves no partial project behind. With overwrite, an
+// existing dir has its files replaced one by one instead. Once ctx is done no
+// further file is written.
+func (m *memFS) commit(ctx context.Context, dir string, overwrite bool) error {
+	if info, err := os.Stat(dir); err == nil {
+		if !overwrite {
+			return fmt.Errorf("%s already exists, use -force to regenerate into it", dir)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		return m.overwrite(ctx, dir)
+	}
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, os.ModePerm); err != nil {
+		return err
+	}
+	tmp := filepath.Join(parent, fmt.Sprintf(".%s.maker-%d", filepath.Base(dir), os.Getpid()))
+	if err := os.Mkdir(tmp, os.ModePerm); err != nil {
+		return err
+	}
+	err := joinErrors(forEach(len(m.paths), func(i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f := m.files[m.paths[i]]
+		name := filepath.Join(tmp, filepath.FromSlash(m.paths[i]))
+		if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(name, f.contents, f.perm)
+	}))
+	if err == nil {
+		err = os.Rename(tmp, dir)
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return nil
+}
+
+// overwrite writes the files over those of dir. Each file is written next to
+// the one it replaces and renamed over it, so none is left half written.
+func (m *memFS) overwrite(ctx context.Context, dir string) error {
+	return joinErrors(forEach(len(m.paths), func(i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f := m.files[m.paths[i]]
+		name := filepath.Join(dir, filepath.FromSlash(m.paths[i]))
+		if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+			return err
+		}
+		tmp := fmt.Sprintf("%s.maker-%d", name, os.Getpid())
+		if err := ioutil.WriteFile(tmp, f.contents, f.perm); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		if err := os.Rename(tmp, name); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return nil
+	}))
+}
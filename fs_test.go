@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemFSCommit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "project")
+	fsys := newMemFS()
+	fsys.WriteFile("Makefile", []byte("old\n"), 0644)
+	fsys.WriteFile("cmd/api/main.go", []byte("package main\n"), 0644)
+	fsys.WriteFile("Makefile", []byte("new\n"), 0744)
+
+	if want := []string{"Makefile", "cmd/api/main.go"}; len(fsys.paths) != len(want) || fsys.paths[0] != want[0] || fsys.paths[1] != want[1] {
+		t.Fatalf("paths = %v, want %v", fsys.paths, want)
+	}
+	if err := fsys.commit(context.Background(), dir, false); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "Makefile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "new\n" {
+		t.Errorf("Makefile = %q, want the last write", contents)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "Makefile")); err != nil || info.Mode().Perm() != 0744 {
+		t.Errorf("Makefile mode = %v, %v, want 0744", info.Mode().Perm(), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cmd", "api", "main.go")); err != nil {
+		t.Error(err)
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Dir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("the parent holds %d entries, want only the project", len(entries))
+	}
+}
+
+func TestMemFSCommitExisting(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fsys := newMemFS()
+	fsys.WriteFile("Makefile", []byte("all:\n"), 0644)
+
+	if err := fsys.commit(context.Background(), dir, false); err == nil {
+		t.Fatal("committing into an existing directory without overwrite succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Makefile")); !os.IsNotExist(err) {
+		t.Errorf("a refused commit wrote the Makefile: %v", err)
+	}
+
+	if err := fsys.commit(context.Background(), dir, true); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"Makefile": "all:\n", "README.md": "mine\n"} {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(contents) != want {
+			t.Errorf("%s = %q, %v, want %q", name, contents, err, want)
+		}
+	}
+}
+
+func TestMemFSCommitCanceled(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "project")
+	fsys := newMemFS()
+	fsys.WriteFile("Makefile", []byte("all:\n"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := fsys.commit(ctx, dir, false); err == nil {
+		t.Fatal("a canceled commit succeeded")
+	}
+	entries, err := ioutil.ReadDir(filepath.Dir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("a canceled commit left %d entries behind", len(entries))
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// executeTimeout bounds how long a single template may run.
+	executeTimeout = 10 * time.Second
+	// maxOutputSize bounds the size of a single rendered template.
+	maxOutputSize = 1 << 20
+)
+
+// allowUnsafeFunctions makes the functions that reach outside the template
+// data (environment, files, commands) available to templates.
+var allowUnsafeFunctions bool
+
+// templateFuncs returns the functions available to templates. The commands
+// that exec runs are killed when ctx is done.
+func templateFuncs(ctx context.Context) template.FuncMap {
+	funcs := template.FuncMap{
+		"tools":     enabledTools,
+		"commands":  enabledCommands,
+		"hints":     requirementHints,
+		"tasks":     tasks,
+		"usedTools": usedTools,
+		"goImage":   goImage,
+		"newerGo":   newerGo,
+		"tr":        translate,
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"trim":      strings.TrimSpace,
+		"replace":   strings.ReplaceAll,
+		"split":     strings.Split,
+		"join":      strings.Join,
+		"contains":  strings.Contains,
+		"hasPrefix": strings.HasPrefix,
+		"hasSuffix": strings.HasSuffix,
+		"default": func(fallback, value interface{}) interface{} {
+			if value == nil || value == "" || value == false {
+				return fallback
+			}
+			return value
+		},
+	}
+
+	unsafe := template.FuncMap{
+		"env": os.Getenv,
+		"readFile": func(path string) (string, error) {
+			contents, err := ioutil.ReadFile(path)
+			return string(contents), err
+		},
+		"exec": func(name string, args ...string) (string, error) {
+			out, err := runCommand(ctx, "", name, args...)
+			return strings.TrimSpace(out), err
+		},
+	}
+	for name, f := range unsafe {
+		if !allowUnsafeFunctions {
+			f = disabled(name)
+		}
+		funcs[name] = f
+	}
+	return funcs
+}
+
+// disabled returns a template function that fails because name is unsafe.
+func disabled(name string) func(...interface{}) (string, error) {
+	return func(...interface{}) (string, error) {
+		return "", fmt.Errorf("%s is disabled, rerun with --allow-unsafe-functions to allow it", name)
+	}
+}
+
+// errOutputTooLarge is returned when a template renders more than
+// maxOutputSize bytes.
+var errOutputTooLarge = fmt.Errorf("template output exceeds %d bytes", maxOutputSize)
+
+// limitedBuffer is a buffer that refuses writes past maxOutputSize, and once
+// ctx is done, so that a template that is given up on stops at its next write.
+type limitedBuffer struct {
+	strings.Builder
+	ctx context.Context
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if b.Len()+len(p) > maxOutputSize {
+		return 0, errOutputTooLarge
+	}
+	return b.Builder.Write(p)
+}
+
+// execute runs templ with data, failing when ctx is done before it finishes,
+// it runs longer than executeTimeout or renders more than maxOutputSize bytes.
+// The template runs with its functions bound to a context done in either
+// case, which kills the commands exec runs and stops its writes, so that no
+// work outlives the call.
+func execute(ctx context.Context, templ *template.Template, data interface{}) (string, error) {
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, executeTimeout)
+	defer cancel()
+	templ = templ.Funcs(templateFuncs(ctx))
+
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		buffer := limitedBuffer{ctx: ctx}
+		err := templ.Execute(&buffer, data)
+		done <- result{buffer.String(), err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-ctx.Done():
+		if err := parent.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.New(templ.Name() + ": template execution timed out after " + executeTimeout.String())
+	}
+}
@@ -1,3 +1,9 @@
 module github.com/grocky/maker
 
-go 1.13
+go 1.16
+
+require (
+	golang.org/x/crypto v0.21.0
+	golang.org/x/term v0.18.0
+	gopkg.in/yaml.v3 v3.0.1
+)
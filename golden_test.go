@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update rewrites the golden files with the rendered Makefiles instead of
+// comparing against them, as in go test -run TestMakefileGolden -update.
+var update = flag.Bool("update", false, "rewrites the golden files of testdata")
+
+// goldenCases are the option sets whose Makefiles are kept in
+// testdata/NAME.golden, covering the project types, languages and the
+// features that add sections to every block.
+var goldenCases = []struct {
+	name   string
+	config map[string]interface{}
+}{
+	{"cli", map[string]interface{}{}},
+	{"cli-test", map[string]interface{}{"test": true, "bench": true, "cover": true, "coverHTML": true, "race": true, "testRace": true, "shadow": true}},
+	{"library", map[string]interface{}{"library": true, "test": true, "bench": true, "fuzz": true, "mod": "example.com/library"}},
+	{"cli-quality", map[string]interface{}{"preset": "quality", "test": true, "cover": true}},
+	{"http-docker", map[string]interface{}{"type": "http", "docker": true, "imageScan": true, "test": true, "mod": "example.com/http-docker"}},
+	{"go-node", map[string]interface{}{"lang": "go+node", "shell": "bash"}},
+	{"go-node-oneshell", map[string]interface{}{"lang": "go+node", "makeFeatures": "4.x"}},
+	{"go-python", map[string]interface{}{"lang": "go+python", "shell": "sh", "makeFeatures": "4.x"}},
+	{"experimental", map[string]interface{}{"mutation": true, "test": true, "locale": "de"}},
+	{"binaries-release", map[string]interface{}{"binaries": "api,worker", "release": true, "goreleaser": true, "mod": "example.com/binaries-release"}},
+	{"monorepo", map[string]interface{}{"type": "monorepo", "mod": "example.com/monorepo"}},
+}
+
+// renderedMakefile returns the Makefile of the project called name generated
+// with config, as written into the project.
+func renderedMakefile(t *testing.T, name string, config map[string]interface{}) []byte {
+	t.Helper()
+	data := templateData(name)
+	if err := applyConfig(data, config); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFeatures(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := renderProject(context.Background(), t.TempDir(), data, &lock{Version: Version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := fsys.files["Makefile"]
+	if !ok {
+		t.Fatal("no Makefile rendered")
+	}
+	return f.contents
+}
+
+func TestMakefileGolden(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			got := renderedMakefile(t, c.name, c.config)
+			path := filepath.Join("testdata", c.name+".golden")
+			if *update {
+				if err := ioutil.WriteFile(path, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v, run go test -run TestMakefileGolden -update to write it", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Makefile differs from %s, run go test -run TestMakefileGolden -update to accept it:\n%s", path, lineDiff(want, got))
+			}
+		})
+	}
+}
+
+func TestMakefileDeterministic(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			first := renderedMakefile(t, c.name, c.config)
+			if second := renderedMakefile(t, c.name, c.config); !bytes.Equal(first, second) {
+				t.Fatalf("rendering twice differs:\n%s", lineDiff(first, second))
+			}
+			lines := strings.Split(string(first), "\n")
+			for i, line := range lines {
+				if strings.TrimRight(line, " \t") != line {
+					t.Errorf("line %d has trailing whitespace: %q", i+1, line)
+				}
+				if i > 0 && line == "" && lines[i-1] == "" && i < len(lines)-1 {
+					t.Errorf("line %d is a second blank line", i+1)
+				}
+			}
+		})
+	}
+}
+
+// lineDiff returns the lines of want and got that differ, as - and + lines.
+func lineDiff(want, got []byte) string {
+	var b strings.Builder
+	lines := splitLines(want)
+	for _, h := range diffLines(lines, splitLines(got)) {
+		for _, line := range lines[h.from:h.to] {
+			b.WriteString("- " + line)
+		}
+		for _, line := range h.lines {
+			b.WriteString("+ " + line)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// experimentalLabel prefixes the help comments of experimental targets, and is
+// kept in front of their translation.
+const experimentalLabel = "[experimental] "
+
+// The built-in message catalogs of -locale, mapping the help comments of the
+// targets to their translation. A catalog of the same name in the
+// templatesDir replaces the built-in one, and comments a catalog leaves out
+// stay in English.
+func init() {
+	fileTemplates["locales/es.yaml"] = `build the binary: compila el binario
+build the library: compila la biblioteca
+run the binary: ejecuta el binario
+run the code generators: ejecuta los generadores de código
+format the codes: formatea el código
+lint the codes: analiza el código con linters
+vet the codes: revisa el código con go vet
+test the codes: prueba el código
+test with benchmarks: prueba con benchmarks
+test with coverage merged across packages: prueba con la cobertura combinada de todos los paquetes
+test and check for race conditions: prueba y detecta condiciones de carrera
+test without network or container access: prueba sin acceso a la red ni a contenedores
+install the tools pinned in tools.yaml: instala las herramientas fijadas en tools.yaml
+generate, build, test and lint the codes: genera, compila, prueba y analiza el código
+print this help message: muestra este mensaje de ayuda
+print the targets and variables as JSON: muestra los objetivos y las variables en JSON
+build the docker image: construye la imagen de docker
+tag and push the release TAG (make tag TAG=v1.2.3): etiqueta y publica la versión TAG (make tag TAG=v1.2.3)
+`
+
+	fileTemplates["locales/de.yaml"] = `build the binary: baut das Binary
+build the library: baut die Bibliothek
+run the binary: führt das Binary aus
+run the code generators: führt die Codegeneratoren aus
+format the codes: formatiert den Code
+lint the codes: prüft den Code mit Lintern
+vet the codes: prüft den Code mit go vet
+test the codes: testet den Code
+test with benchmarks: testet mit Benchmarks
+test with coverage merged across packages: testet mit über alle Pakete zusammengeführter Abdeckung
+test and check for race conditions: testet und sucht nach Race Conditions
+test without network or container access: testet ohne Netzwerk- oder Containerzugriff
+install the tools pinned in tools.yaml: installiert die in tools.yaml festgelegten Werkzeuge
+generate, build, test and lint the codes: generiert, baut, testet und prüft den Code
+print this help message: zeigt diese Hilfe an
+print the targets and variables as JSON: gibt die Targets und Variablen als JSON aus
+build the docker image: baut das Docker-Image
+tag and push the release TAG (make tag TAG=v1.2.3): taggt und pusht das Release TAG (make tag TAG=v1.2.3)
+`
+}
+
+// setLocale sets the message catalog of the template data from -locale, which
+// is empty for English.
+func setLocale(data map[string]interface{}) error {
+	catalog := map[string]string{}
+	locale, _ := data["locale"].(string)
+	if locale != "" && locale != "en" {
+		name := "locales/" + locale + ".yaml"
+		text, ok, err := overrideTemplate(name, data)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			text, ok = projectTemplates(data).template(name)
+		}
+		if !ok {
+			return fmt.Errorf("no message catalog for locale %q, add %s to the templatesDir", locale, name)
+		}
+		if err := yaml.Unmarshal([]byte(text), &catalog); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	data["catalog"] = catalog
+	return nil
+}
+
+// translate returns the translation of message in catalog, or message when
+// it has none.
+func translate(catalog map[string]string, message string) string {
+	if t, ok := catalog[message]; ok && t != "" {
+		return t
+	}
+	return message
+}
+
+// translateHelp translates the help comments of the rules of a Makefile.
+func translateHelp(contents []byte, catalog map[string]string) []byte {
+	if len(catalog) == 0 {
+		return contents
+	}
+	lines := strings.Split(string(contents), "\n")
+	for i, line := range lines {
+		m := makeRule.FindStringSubmatch(line)
+		if m == nil || m[2] == "" || strings.HasPrefix(line, "\t") || makeAssignment.MatchString(line) {
+			continue
+		}
+		message := strings.TrimPrefix(m[2], experimentalLabel)
+		if t := translate(catalog, message); t != message {
+			lines[i] = m[1] + " ## " + m[2][:len(m[2])-len(message)] + t
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
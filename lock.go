@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lockFile records what a project was generated with and from. It is written
+// into the generated project, read from the project directory on regeneration
+// and read by maker doctor.
+const lockFile = ".maker.lock"
+
+// lock is the contents of a lock file.
+type lock struct {
+	Version string `yaml:"version"`
+	// Type and Options are the project type and the enabled options the
+	// project was generated with.
+	Type    string   `yaml:"type,omitempty"`
+	Options []string `yaml:"options,omitempty"`
+	// Channel is the template channel the project was generated from,
+	// empty for stable. Regenerating the project keeps it.
+	Channel string `yaml:"channel,omitempty"`
+	// Templates is the checksum of the templates in effect when the project
+	// was rendered, and Preset the preset it was generated with, which
+	// maker audit reports.
+	Templates string       `yaml:"templates,omitempty"`
+	Preset    string       `yaml:"preset,omitempty"`
+	Sources   []lockSource `yaml:"sources,omitempty"`
+	Files     []lockedFile `yaml:"files,omitempty"`
+}
+
+// lockedFile is a generated file and the checksum of its generated contents,
+// with the path relative to the project.
+type lockedFile struct {
+	Path   string `yaml:"path"`
+	SHA256 string `yaml:"sha256"`
+}
+
+// lockSource is a remote source and the checksum it was verified against.
+type lockSource struct {
+	URL    string `yaml:"url"`
+	SHA256 string `yaml:"sha256"`
+}
+
+// readLock reads the lock file at path. A missing file is an empty lock.
+func readLock(path string) (*lock, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &lock{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var l lock
+	if err := yaml.Unmarshal(contents, &l); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &l, nil
+}
+
+// enabled reports whether the project was generated with option.
+func (l *lock) enabled(option string) bool {
+	return contains(l.Options, option)
+}
+
+// checksum returns the recorded checksum of url.
+func (l *lock) checksum(url string) (string, bool) {
+	for _, source := range l.Sources {
+		if source.URL == url {
+			return source.SHA256, true
+		}
+	}
+	return "", false
+}
+
+// record sets the checksum of url.
+func (l *lock) record(url, sum string) {
+	for i, source := range l.Sources {
+		if source.URL == url {
+			l.Sources[i].SHA256 = sum
+			return
+		}
+	}
+	l.Sources = append(l.Sources, lockSource{url, sum})
+}
+
+// bytes returns the lock encoded as YAML.
+func (l *lock) bytes() ([]byte, error) {
+	return yaml.Marshal(l)
+}
@@ -1,118 +1,92 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"flag"
 	"fmt"
-	"html/template"
-	"io/ioutil"
 	"os"
-	"regexp"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
 )
 
-const makefileTemplate = `.DEFAULT_GOAL := help
-
-BIN = $(CURDIR)/bin
-VERSION ?= $(shell git describe --tags --always --dirty --match=v* 2> /dev/null || echo v0)
-
-$(BIN):
-	@mkdir -p $@
-
-.PHONY:phony
-
-fmt: phony ## format the codes
-	@go fmt ./...
-
-lint: phony fmt ## lint the codes
-	@golint ./...
-
-vet: phony lint ## vet the codes
-	@go vet ./...
-{{- if .shadow}}	@shadow ./...{{end}}
-
-{{ if not .library}}
-build: phony vet | $(BIN) ## build the binary
-	@go build \
-		-tags release \
-		-ldflags '-X main.Version=$(VERSION)' \
-		-o $(BIN)/ ./...
-
-run: phony vet ## run the binary
-	@go run main.go
-{{ else}}
-build: phony vet ## build the library
-	@go build ./...
-{{end}}
-
-clean: phony
-	rm -rf $(BIN)
-
-{{- if .test}}
-test: phony vet ## test the codes
-	@go test -v ./...
-{{ end }}
-
-{{- if .bench}}
-bench: phony vet ## test with benchmarks
-	@go test -v -bench=. -benchmem ./...
-{{ end }}
-
-{{- if and .test .cover}}
-test-cover: phony vet ## test with coverage
-	@go test -v -cover ./...
-{{ end }}
-
-{{- if and .test .coverHTML}}
-test-cover-html: phony vet ## test with coverage in an HTML view
-	@go test -v -cover -coverprofile=c.out ./...
-	@go tool cover -html=c.out
-{{ end }}
-
-{{- if .testRace}}
-test-race: phony vet ## test and check for race conditions
-	@go test -race ./...
-{{ end }}
-
-{{- if .race}}
-build-race: phony vet ## build and check for race conditions
-	@go build -race
-{{ end }}
-
-{{- if .cpuProfile}}
-test-cpu: phony vet ## test and profile CPU
-	@go test {{if .bench}}-bench=. -benchmem{{end}} -cpuprofile cpu.out ./...
-	@go tool pprof cpu.out
-{{ end }}
-
-{{- if .memProfile}}
-test-mem: phony vet ## test and profile memory
-	@go test {{if .bench}}-bench=. -benchmem{{end}} -memprofile mem.out ./...
-	@go tool pprof mem.out
-{{ end }}
-
-GREEN  := $(shell tput -Txterm setaf 2)
-RESET  := $(shell tput -Txterm sgr0)
-
-help: phony ## print this help message
-	@awk -F ':|##' '/^[^\t].+?:.*?##/ { printf "${GREEN}%-20s${RESET}%s\n", $$1, $$NF }' $(MAKEFILE_LIST)
-`
-
 // Version is the version of the binary. This is set by -ldflags during the build.
 var Version = "dev"
 
 func main() {
-	t := flag.Bool("test", false, "Adds test to makefile")
-	b := flag.Bool("bench", false, "Adds bench to makefile")
-	s := flag.Bool("shadow", false, "Adds shadow to makefile")
-	c := flag.Bool("cover", false, "Adds cover to makefile")
-	ch := flag.Bool("coverHTML", false, "Adds cover HTML to makefile")
-	cp := flag.Bool("cpuProfile", false, "Adds CPU profiling to makefile")
-	mp := flag.Bool("memProfile", false, "Adds Memory profiling to makefile")
-	r := flag.Bool("race", false, "Adds race checking to makefile")
-	tr := flag.Bool("testRace", false, "Adds race checking tests to makefile")
-	l := flag.Bool("library", false, "Creates a library makefile")
-	m := flag.String("mod", "", "Creates a mod file. Specify the source control path (github.com/user/project).")
-	v := flag.Bool("version", false, "Displays the version of this binary")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "render":
+			render(os.Args[2:])
+			return
+		case "validate":
+			validate(os.Args[2:])
+			return
+		case "init":
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		case "add-service":
+			addService(os.Args[2:])
+			return
+		case "clean-generated":
+			cleanGenerated(os.Args[2:])
+			return
+		case "adopt":
+			adopt(os.Args[2:])
+			return
+		case "fmt":
+			makefmt(os.Args[2:])
+			return
+		case "list":
+			list(os.Args[2:])
+			return
+		case "doctor":
+			doctor(os.Args[2:])
+			return
+		case "check-make":
+			checkMakeCompat(os.Args[2:])
+			return
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "adr":
+			adr(os.Args[2:])
+			return
+		case "snapshot":
+			snapshotCommand(os.Args[2:])
+			return
+		case "restore":
+			restoreCommand(os.Args[2:])
+			return
+		case "convert":
+			convert(os.Args[2:])
+			return
+		case "verify":
+			verifyCommand(os.Args[2:])
+			return
+		case "bump-go":
+			bumpGoCommand(os.Args[2:])
+			return
+		case "audit":
+			auditCommand(os.Args[2:])
+			return
+		case "schema":
+			schemaCommand(os.Args[2:])
+			return
+		case "template":
+			templateCommand(os.Args[2:])
+			return
+		case "batch":
+			batchMode = true
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+	}
+
+	cf, v := defineFlags(flag.CommandLine)
+	flag.Usage = usage
 
 	flag.Parse()
 
@@ -122,65 +96,583 @@ func main() {
 	}
 
 	if len(flag.Args()) != 1 {
-		fmt.Println("Expected use: maker DIRNAME")
+		if batchMode {
+			fmt.Println("Expected use: maker batch [flags] SPEC")
+		} else {
+			fmt.Println("Expected use: maker DIRNAME")
+		}
 		os.Exit(1)
 	}
-	dirName := flag.Arg(0)
-
-	templ := template.Must(template.New("makefile").Parse(makefileTemplate))
-
-	var buffer bytes.Buffer
-	err := templ.Execute(&buffer, map[string]interface{}{
-		"test":       *t,
-		"bench":      *b,
-		"shadow":     *s,
-		"cover":      *c,
-		"coverHTML":  *ch,
-		"cpuProfile": *cp,
-		"memProfile": *mp,
-		"race":       *r,
-		"testRace":   *tr,
-		"library":    *l,
-	})
+
+	user, err := loadConfig(userConfigFile())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	project, err := loadConfig(*cf)
 	if err != nil {
-		panic(err)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defaults := []map[string]interface{}{envConfig(), user, project}
+	overrides := flagConfig(flag.CommandLine)
+	ctx, stop := interruptContext()
+	defer stop()
+	startReport()
+
+	if batchMode {
+		err = batch(ctx, flag.Arg(0), defaults, overrides, trustedKeys(user))
+	} else {
+		dirName := flag.Arg(0)
+		config := mergeConfigs(append(defaults, overrides)...)
+		summarize = true
+		err = newProject(ctx, dirName, filepath.Base(dirName), config, trustedKeys(user))
+	}
+	if reportErr := writeReport(); err == nil {
+		err = reportErr
 	}
-	err = os.Mkdir(dirName, os.ModePerm)
 	if err != nil {
-		panic(err)
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	regex, err := regexp.Compile("\n\n+")
+}
+
+// defineFlags defines the flags of maker on flags, one per option along with
+// those changing how it runs, and returns the -config and -version ones.
+func defineFlags(flags *flag.FlagSet) (config *string, version *bool) {
+	for _, f := range features {
+		usage := f.usage
+		if f.experimental {
+			usage += " (experimental, run with EXPERIMENTAL=1)"
+		}
+		flags.Bool(f.name, false, usage)
+	}
+	flags.String("mod", "", "Creates a mod file. Specify the source control path (github.com/user/project).")
+	flags.String("type", "cli", "Creates a project of this type (cli, http, monorepo)")
+	flags.String("shell", "", "Sets the shell recipes run with (bash with pipefail, or POSIX sh)")
+	flags.String("format", "", "Generates task scripts mirroring the makefile for machines without make (psake, scripts)")
+	flags.String("lang", "", "Adds the targets of a Node frontend or Python scripts to the makefile (go, go+node, go+python)")
+	flags.String("assertions", "", "Writes the generated tests with this assertion library (stdlib, testify, gotest.tools)")
+	flags.String("go-versions", "", "Tests with these comma separated go versions in the CI workflow (minimum, oldstable, stable, tip, 1.N)")
+	flags.String("make-features", "", "Uses features of newer GNU Make versions in the makefile (4.x)")
+	flags.String("author", "", "Names the copyright holder in the LICENSE file")
+	flags.String("github", "", "Derives -modulePrefix as github.com/GITHUB when it is not set")
+	flags.String("vcs-host", "", "Hosts the project on github, gitlab, bitbucket or a self-hosted gitea, inferred from the module path by default")
+	flags.String("ci-provider", "", "Runs the CI pipeline on github, gitea, gitlab, bitbucket or circleci, the CI of the -vcs-host by default")
+	flags.String("modulePrefix", "", "Derives the mod file path as PREFIX/DIRNAME when -mod is not set")
+	flags.String("preset", "", "Enables a preset set of options (library, profiling, quality, testing)")
+	flags.String("description", "", "Describes the project in the files that name it")
+	flags.String("locale", "", "Translates the help of the make targets with the message catalog of this locale, built in for es and de")
+	flags.Int("port", 0, "Sets the port an http project listens on (8080)")
+	flags.String("strategy", "", "Resolves the managed files modified since they were generated when regenerating with -force (ask, mine, new, merge), asking on a terminal and keeping the modified ones otherwise")
+	flags.String("naming", "", "Names the project, its binaries, their directories and images in this convention (kebab, snake, camel)")
+	flags.String("binaries", "", "Builds these comma separated binaries from cmd/NAME instead of one from main.go")
+	flags.String("keywords", "", "Lists comma separated keywords describing the project")
+	flags.String("homepage", "", "Links the project to this URL, the web page of its repository by default")
+	flags.String("registry", "", "Pushes the docker image to this registry (ghcr.io/team)")
+	flags.String("team", "", "Names the team owning the project")
+	flags.String("license", "", "Creates a LICENSE file (BSD-3-Clause, ISC, MIT)")
+	flags.String("templatesDir", "", "Reads templates from this directory in place of the built-in ones of the same name")
+	flags.String("channel", "stable", "Generates from the released templates or, with canary, the pre-release ones of the main branch (stable, canary)")
+	flags.Bool("offline", false, "Disables all network access and fails if a selected feature would require it")
+	flags.Bool("report-md", false, "Documents the targets, the commands and tools they need and the variables of the makefile in "+buildDocFile)
+	flags.BoolVar(&allowUnsafeFunctions, "allow-unsafe-functions", false, "Allows templates to use the env, readFile and exec functions")
+	flags.BoolVar(&force, "force", false, "Regenerates into an existing directory, leaving secrets and the files of .makerignore and .gitignore untouched")
+	flags.BoolVar(&noExec, "no-exec", false, "Prints the external commands that would change something instead of running them")
+	flags.BoolVar(&updateSources, "update-sources", false, "Accepts the checksums the presetSource publishes now in place of those recorded in "+lockFile+", trusting them as on first use")
+	flags.BoolVar(&dryRun, "dry-run", false, "Prints the files that would be generated without writing them")
+	flags.StringVar(&reportFile, "report", "", "Writes a local JSON report of what was generated and how long it took to this file")
+	flags.BoolVar(&plain, "plain", false, "Prints the generated files without colors or glyphs")
+	config = flags.String("config", projectConfigFile, "Reads options from a config file")
+	version = flags.Bool("version", false, "Displays the version of this binary")
+	return config, version
+}
+
+// interruptContext returns a context that is done once maker is interrupted or
+// terminated, so that generation stops and leaves no partial project behind.
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// batchMode is set when running maker batch.
+var batchMode bool
+
+// newProject generates the project called name into the new directory dir
+// from a merged config, stopping when ctx is done. Regenerating into a dir
+// with a go.mod keeps its module path and go version.
+func newProject(ctx context.Context, dir, name string, config map[string]interface{}, keys []trustedKey) error {
+	if err := moduleConfig(filepath.Join(dir, "go.mod"), config); err != nil {
+		return err
+	}
+	// Regenerating a project keeps the channel it was generated from.
+	if _, ok := config["channel"]; !ok {
+		previous, err := readLock(filepath.Join(dir, lockFile))
+		if err != nil {
+			return err
+		}
+		if previous.Channel != "" {
+			config["channel"] = previous.Channel
+		}
+	}
+	data, generated, err := projectData(ctx, dir, name, config, keys)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	// Regenerating a project with -force keeps the module path it has.
+	if !force {
+		warnTakenModule(ctx, data["module"].(string))
+	}
+	if err := generate(ctx, dir, data, generated); err != nil {
+		return err
 	}
-	cleanBuf := regex.ReplaceAll(buffer.Bytes(), []byte("\n\n"))
-	err = ioutil.WriteFile(dirName+string(os.PathSeparator)+"Makefile", cleanBuf, 0744)
+	if summarize && !dryRun {
+		summary(dir, data)
+	}
+	return nil
+}
+
+// projectData returns the template data of the project called name from a
+// merged config, along with the lock recording the presetSource it loaded.
+// The sources are verified against the checksums of the lock file of the
+// project in dir, and the templates they add are kept in the data, so that
+// they apply to this project only.
+func projectData(ctx context.Context, dir, name string, config map[string]interface{}, keys []trustedKey) (map[string]interface{}, *lock, error) {
+	if err := checkProjectName(name); err != nil {
+		return nil, nil, err
+	}
+	offline, _ = config["offline"].(bool)
+	trusted, err := readLock(filepath.Join(dir, lockFile))
 	if err != nil {
-		panic(err)
+		return nil, nil, err
+	}
+	if updateSources {
+		trusted.Sources = nil
+	}
+	generated := &lock{Version: Version}
+	set := &templateSet{}
+	// The preset source is loaded after the channel so that its templates
+	// take precedence.
+	channel, _ := config["channel"].(string)
+	if err := loadChannel(ctx, channel, generated, set); err != nil {
+		return nil, nil, err
+	}
+	if source, _ := config["presetSource"].(string); source != "" {
+		sum, err := loadPresetSource(ctx, source, trusted, keys, set)
+		if err != nil {
+			return nil, nil, err
+		}
+		generated.record(source, sum)
 	}
-	if !(*l) {
-		err = ioutil.WriteFile(dirName+string(os.PathSeparator)+"main.go", []byte(`package main
 
-func main() {
+	data := templateData(name)
+	data["templateSet"] = set
+	if err := applyConfig(data, config); err != nil {
+		return nil, nil, err
+	}
+	if err := checkFeatures(ctx, data); err != nil {
+		return nil, nil, err
+	}
+	return data, generated, nil
 }
-`), 0744)
-	} else {
-		err = ioutil.WriteFile(dirName+string(os.PathSeparator)+dirName+".go", []byte("package "+dirName+"\n"), 0744)
+
+// checkProjectName returns an error unless name is a single path element, as
+// the base name of the directory a project is generated into is, since the
+// rendered paths are joined with it.
+func checkProjectName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid project name %q, expected a name without path separators", name)
+	}
+	return nil
+}
+
+// summarize prints a summary after generating a project, which batch and
+// add-service leave out.
+var summarize bool
+
+// usage prints the command line help, including where configuration is read
+// from.
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Expected use: maker [init] [flags] DIRNAME
+       maker render [-flags OPTIONS] [TEMPLATE]
+       maker batch [flags] SPEC
+       maker add-service [-type TYPE] [-no-exec] NAME
+       maker validate [FILE...]
+       maker fmt [-check] [MAKEFILE...]
+       maker convert -to taskfile|justfile [-o FILE] [-force] [MAKEFILE]
+       maker adopt [-dry-run] [-plain] [DIR]
+       maker list
+       maker schema [-json]
+       maker doctor [-plain] [DIR]
+       maker check-make [-plain] [DIR]
+       maker verify [-plain] [DIR]
+       maker serve [-addr ADDR]
+       maker adr new [-dir DIR] "TITLE"
+       maker template lint [-plain] BUNDLE
+       maker template bundle [-o FILE]
+       maker snapshot [-o FILE] [DIR]
+       maker restore [-i FILE] [DIR]
+       maker clean-generated [-force] [DIR]
+       maker bump-go [-dry-run] [-plain] VERSION [DIR]
+       maker audit [-json] [DIR...]
+
+Configuration is read from the following sources. Later sources take
+precedence over earlier ones:
+
+  1. the MAKER_PRESET, MAKER_MODULE_PREFIX, MAKER_LICENSE and
+     MAKER_TEMPLATES_DIR environment variables
+  2. the user config file (%s)
+  3. the project config file (%s, or -config)
+  4. flags
+
+Flags:
+`, userConfigFile(), projectConfigFile)
+	flag.PrintDefaults()
+}
+
+// templateData returns the data available to the templates with every option
+// disabled.
+func templateData(name string) map[string]interface{} {
+	data := map[string]interface{}{
+		"name":         name,
+		"type":         "cli",
+		"author":       "",
+		"github":       "",
+		"vcsHost":      "",
+		"ciProvider":   "",
+		"module":       "",
+		"modulePrefix": "",
+		"preset":       "",
+		"license":      "",
+		"templatesDir": "",
+		"makeFeatures": "",
+		"goVersions":   "minimum,oldstable,stable",
+		"minGoVersion": minGoVersion,
+		"workspace":    "",
+		"shell":        "",
+		"lang":         "go",
+		"naming":       "",
+		"format":       "",
+		"description":  "",
+		"port":         0,
+		"binaries":     "",
+		"keywords":     "",
+		"homepage":     "",
+		"registry":     "",
+		"team":         "",
+		"assertions":   "stdlib",
+		"locale":       "",
+		"catalog":      map[string]string{},
+		"year":         time.Now().Year(),
+		"date":         time.Now().Format("2006-01-02"),
+	}
+	for _, f := range features {
+		data[f.name] = false
 	}
+	setVCS(data)
+	setPipeline(data)
+	setMeta(data)
+	return data
+}
+
+// file is a project file rendered from a template.
+type file struct {
+	path     string
+	template string
+	perm     os.FileMode
+}
+
+// generate renders the project files, followed by the lock file recording what
+// they were generated with, and writes them into the new directory dir, or
+// over the files of an existing one with -force. With -dry-run it only reports
+// them.
+func generate(ctx context.Context, dir string, data map[string]interface{}, generated *lock) error {
+	start := time.Now()
+	conflicted = nil
+	fsys, err := renderProject(ctx, dir, data, generated)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	statuses := map[string]status{}
+	for _, path := range fsys.paths {
+		switch existing := exists(filepath.Join(dir, filepath.FromSlash(path))); {
+		case dryRun && existing:
+			statuses[path] = wouldUpdate
+		case dryRun:
+			statuses[path] = planned
+		case existing:
+			statuses[path] = updated
+		default:
+			statuses[path] = created
+		}
+	}
+	rendered := time.Now()
+	if !dryRun {
+		if err := fsys.commit(ctx, dir, force); err != nil {
+			return err
+		}
+	}
+	recordProject(dir, data, fsys, rendered.Sub(start), time.Since(rendered))
+	for _, path := range fsys.paths {
+		report(statuses[path], filepath.Join(dir, filepath.FromSlash(path)))
+	}
+	for _, path := range conflicted {
+		report(failed, path+" has merge conflicts, resolve them between the conflict markers")
+	}
+	if workspace, _ := data["workspace"].(string); workspace != "" {
+		if err := shareTools(filepath.Join(dir, filepath.FromSlash(workspace), toolsFile), enabledTools(data)); err != nil {
+			return err
+		}
+	}
+	if len(conflicted) > 0 {
+		return fmt.Errorf("regenerating %s left merge conflicts to resolve", dir)
+	}
+	return nil
+}
+
+// renderProject renders the files of the project to be generated into dir,
+// followed by the lock file, into memory.
+func renderProject(ctx context.Context, dir string, data map[string]interface{}, generated *lock) (*memFS, error) {
+	var files []file
+	switch {
+	case data["type"] == "monorepo":
+		files = append(files,
+			file{"Makefile", "workspace/Makefile", 0744},
+			file{"go.work", "go.work", 0644},
+			file{toolsFile, toolsFile, 0644},
+			file{"scripts/changed-modules.sh", "workspace/changed-modules.sh", 0755})
+	case data["library"] == true:
+		files = append(files, file{"Makefile", "Makefile", 0744})
+	case data["binaries"] != "":
+		files = append(files, file{"Makefile", "Makefile", 0744})
+		for _, binary := range data["meta"].(projectMeta).Binaries {
+			files = append(files, file{"cmd/" + binary + "/main.go", "main.go", 0744})
+		}
+	default:
+		files = append(files, file{"Makefile", "Makefile", 0744}, file{"main.go", "main.go", 0744})
+	}
+	if data["type"] != "monorepo" {
+		// The tools of a module of a workspace are pinned in the tools
+		// file of its root.
+		if data["workspace"] == "" {
+			files = append(files, file{toolsFile, toolsFile, 0644})
+		}
+		for _, f := range enabledFeatures(data) {
+			files = append(files, f.files...)
+		}
+	}
+	if data["module"] != "" {
+		files = append(files, file{"go.mod", "go.mod", 0744})
+	}
+	// The Dockerfile builds the binary, which libraries and monorepos have none of.
+	if data["docker"] == true && data["library"] != true && data["type"] != "monorepo" {
+		files = append(files, file{"Dockerfile", "Dockerfile", 0644}, file{".dockerignore", ".dockerignore", 0644})
+	}
+	// goreleaser builds the images from the binaries it has already built.
+	if data["docker"] == true && data["goreleaser"] == true {
+		files = append(files, file{"Dockerfile.release", "Dockerfile.release", 0644})
+	}
+	switch {
+	case data["type"] == "monorepo":
+	case data["lang"] == "go+node":
+		files = append(files, file{"web/package.json", "web/package.json", 0644})
+	case data["lang"] == "go+python":
+		files = append(files, file{"requirements-dev.txt", "requirements-dev.txt", 0644})
+	}
+	switch {
+	case data["type"] == "monorepo":
+	case data["format"] == "psake":
+		files = append(files, file{"psakefile.ps1", "psakefile.ps1", 0644})
+	case data["format"] == "scripts":
+		files = append(files, file{"scripts/tasks.sh", "tasks.sh", 0755}, file{"scripts/tasks.ps1", "tasks.ps1", 0644})
+	}
+	// The run configurations of JetBrains IDEs hold one target each.
+	if data["jetbrains"] == true && data["type"] != "monorepo" {
+		for _, t := range tasks(data) {
+			files = append(files, file{".run/" + t.Name + ".run.xml", "jetbrains/" + t.Name + ".run.xml", 0644})
+		}
+		if data["debug"] == true {
+			files = append(files, file{".run/attach.run.xml", "jetbrains/attach.run.xml", 0644})
+		}
+	}
+	if license, _ := data["license"].(string); license != "" {
+		files = append(files, file{"LICENSE", "licenses/" + license, 0644})
+	}
+	// A doc.go is only worth having with a description to put in it, and
+	// would be a main package without a main function next to cmd/.
+	if data["description"] != "" && data["type"] != "monorepo" && data["binaries"] == "" {
+		files = append(files, file{"doc.go", "doc.go", 0644})
+	}
+	files = append(files, file{"README.md", "README.md", 0644}, file{".gitignore", ".gitignore", 0644})
+
+	// Regenerating into an existing project leaves its secrets and the files
+	// it ignores untouched.
+	var protected protection
+	previous := &lock{}
+	if force {
+		var err error
+		if protected, err = readProtection(dir); err != nil {
+			return nil, err
+		}
+		if previous, err = readLock(filepath.Join(dir, lockFile)); err != nil {
+			return nil, err
+		}
+	}
+
+	var names []string
+	var pending []file
+	for _, f := range files {
+		name, err := renderPath(f.path, data)
+		if err == nil {
+			f.template, err = renderPath(f.template, data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if protected.protects(name) {
+			report(skipped, filepath.Join(dir, filepath.FromSlash(name))+" is protected")
+			continue
+		}
+		names = append(names, name)
+		pending = append(pending, f)
 	}
-	if *m != "" {
-		err = ioutil.WriteFile(dirName+string(os.PathSeparator)+"go.mod", []byte(fmt.Sprintf(`module %s
 
-go 1.14
-`, *m)), 0744)
+	// The files are rendered concurrently, then written in order so the
+	// lock file and reports list them as before.
+	outs := make([][]byte, len(pending))
+	errs := forEach(len(pending), func(i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		outs[i], err = renderTemplate(ctx, pending[i].template, data)
+		return err
+	})
+	for i, err := range errs {
 		if err != nil {
-			panic(err)
+			report(failed, filepath.Join(dir, filepath.FromSlash(names[i])))
+		}
+	}
+	if err := joinErrors(errs); err != nil {
+		return nil, err
+	}
+	fsys := newMemFS()
+	for i, name := range names {
+		// The lock records the managed part only, and regenerating keeps
+		// the edits below the preserve marker of the file it replaces.
+		if path.Base(name) == "Makefile" {
+			outs[i] = annotateRequirements(outs[i], enabledCommands(data))
+			outs[i] = translateHelp(outs[i], data["catalog"].(map[string]string))
+		}
+		managed := addMarkers(name, outs[i])
+		contents, sum := managed, sha256Sum(managed)
+		if force {
+			// A file modified since it was generated is resolved with
+			// the strategy, keeping it as it is records its checksum
+			// again.
+			resolved, kept, err := resolveModified(ctx, dir, name, managed, data, previous)
+			if err != nil {
+				return nil, err
+			}
+			user, err := preservedEdits(dir, name)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(resolved[:len(resolved):len(resolved)], user...)
+			if kept != "" {
+				contents, sum = nil, kept
+			}
+		}
+		if contents != nil {
+			if err := fsys.WriteFile(name, contents, pending[i].perm); err != nil {
+				return nil, err
+			}
+		}
+		generated.Files = append(generated.Files, lockedFile{name, sum})
+		if name == "Makefile" && data["reportMd"] == true && !protected.protects(buildDocFile) {
+			doc := buildDoc(outs[i], data)
+			if err := fsys.WriteFile(buildDocFile, doc, 0644); err != nil {
+				return nil, err
+			}
+			generated.Files = append(generated.Files, lockedFile{buildDocFile, sha256Sum(doc)})
+		}
+	}
+	generated.Type = data["type"].(string)
+	generated.Preset, _ = data["preset"].(string)
+	templates, err := templatesChecksum(data)
+	if err != nil {
+		return nil, err
+	}
+	generated.Templates = templates
+	for _, f := range enabledFeatures(data) {
+		generated.Options = append(generated.Options, f.name)
+	}
+	out, err := generated.bytes()
+	if err == nil {
+		err = fsys.WriteFile(lockFile, out, 0644)
+	}
+	if err != nil {
+		report(failed, filepath.Join(dir, lockFile))
+		return nil, err
+	}
+	return fsys, nil
+}
+
+// renderPath renders the path of a file, which may use the template data.
+func renderPath(path string, data map[string]interface{}) (string, error) {
+	if !strings.Contains(path, "{{") {
+		return path, nil
+	}
+	t, err := template.New(path).Parse(path)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// render prints a built-in or custom template rendered with the given options.
+func render(args []string) {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	f := flags.String("flags", "", "Comma separated options to enable (test,bench,library)")
+	n := flags.String("name", "project", "The project name passed to the template")
+	m := flags.String("mod", "", "The module path passed to the template")
+	flags.BoolVar(&allowUnsafeFunctions, "allow-unsafe-functions", false, "Allows templates to use the env, readFile and exec functions")
+	flags.Parse(args)
+
+	if len(flags.Args()) > 1 {
+		fmt.Println("Expected use: maker render [-flags OPTIONS] [TEMPLATE]")
+		os.Exit(1)
+	}
+	name := "Makefile"
+	if len(flags.Args()) == 1 {
+		name = flags.Arg(0)
+	}
+
+	data := templateData(*n)
+	data["module"] = *m
+	setVCS(data)
+	setMeta(data)
+	if *f != "" {
+		for _, option := range strings.Split(*f, ",") {
+			option = strings.TrimSpace(option)
+			if !isOption(option) {
+				fmt.Printf("Unknown option: %s\n", option)
+				os.Exit(1)
+			}
+			data[option] = true
 		}
 	}
-	err = ioutil.WriteFile(dirName+string(os.PathSeparator)+".gitignore", []byte(`bin/`), 0644)
+	// The pipeline runs the steps of the options enabled.
+	setPipeline(data)
+
+	out, err := renderTemplate(context.Background(), name, data)
 	if err != nil {
-		panic(err)
+		fmt.Println(err)
+		os.Exit(1)
 	}
+	os.Stdout.Write(out)
 }
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	// makeAssignment matches a variable assignment, capturing its name,
+	// operator and value.
+	makeAssignment = regexp.MustCompile(`^((?:export\s+|override\s+)*[A-Za-z0-9_.-]+)\s*(:::=|::=|:=|\?=|\+=|!=|=)\s*(.*)$`)
+	// makeRule matches a rule line, capturing the targets with their
+	// prerequisites and the help comment.
+	makeRule = regexp.MustCompile(`^([^\s#=][^=]*?::?(?:(?:[^=#]|#[^#]).*?)?)\s*(?:##\s*(.*))?$`)
+	// makePhony matches a .PHONY declaration, capturing its targets.
+	makePhony = regexp.MustCompile(`^\.PHONY\s*:(.*)$`)
+	// makeConditionalDirective matches any line of a conditional, which may
+	// be indented with spaces within a recipe without being a line of it.
+	makeConditionalDirective = regexp.MustCompile(`^(?:ifeq|ifneq|ifdef|ifndef|else|endif)(?:\s|$)`)
+	// makeInclude matches an include directive, which ends a recipe.
+	makeInclude = regexp.MustCompile(`^(?:-?include|sinclude)\s`)
+)
+
+// helpColumn is the widest rule the help comments of a formatted Makefile are
+// aligned after. The comments of wider rules follow them after a single space
+// rather than pushing the column past it.
+const helpColumn = 40
+
+// makefmt formats the Makefiles given, the one in the working directory by
+// default, rewriting them in place or with -check only reporting those that
+// are not formatted.
+func makefmt(args []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "Reports the Makefiles that are not formatted without rewriting them")
+	flags.Parse(args)
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"Makefile"}
+	}
+	unformatted := false
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		out := formatMakefile(contents)
+		if bytes.Equal(out, contents) {
+			continue
+		}
+		if *check {
+			fmt.Println(path)
+			unformatted = true
+			continue
+		}
+		info, err := os.Stat(path)
+		if err == nil {
+			err = ioutil.WriteFile(path, out, info.Mode().Perm())
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if unformatted {
+		os.Exit(1)
+	}
+}
+
+// formatMakefile normalizes a Makefile to the conventions of the generated
+// ones: recipes indented with a tab, a single space around assignment
+// operators, help comments aligned into one column, one .PHONY declaration and
+// single blank lines between sections. Variables keep their order, since moving an
+// assignment changes what the immediate expansions before it see, and their
+// values keep any trailing whitespace, which is part of them. It is only
+// stripped from recipes, comments and rules.
+func formatMakefile(contents []byte) []byte {
+	var lines []string
+	var phony []string
+	phonyAt := -1
+	phonyCount := 0
+	inRecipe, inDefine, continued := false, false, false
+	// helps are the rules with help comments, by their index in lines.
+	helps := map[int][2]string{}
+
+	for _, line := range strings.Split(strings.TrimRight(string(contents), "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		wasContinued := continued
+		continued = strings.HasSuffix(line, "\\")
+
+		switch trimmed := strings.TrimLeft(line, " \t"); {
+		case wasContinued:
+		case inDefine:
+			if strings.HasPrefix(trimmed, "endef") {
+				inDefine = false
+			}
+		case strings.HasPrefix(line, "define ") || strings.HasPrefix(line, "define\t"):
+			inDefine = true
+			inRecipe = false
+		case trimmed == "":
+			line = ""
+		case strings.HasPrefix(line, "\t"):
+			line = trimTrailing(line)
+		case makeConditionalDirective.MatchString(trimmed):
+		case inRecipe && strings.HasPrefix(line, " ") && !makeInclude.MatchString(trimmed):
+			line = "\t" + trimTrailing(trimmed)
+		case strings.HasPrefix(line, "#"):
+			line = trimTrailing(line)
+		case makePhony.MatchString(line):
+			phonyCount++
+			for _, target := range strings.Fields(makePhony.FindStringSubmatch(line)[1]) {
+				if !contains(phony, target) {
+					phony = append(phony, target)
+				}
+			}
+			if phonyAt >= 0 {
+				continue
+			}
+			phonyAt = len(lines)
+		case makeAssignment.MatchString(trimmed):
+			m := makeAssignment.FindStringSubmatch(trimmed)
+			line = strings.Join(strings.Fields(m[1]), " ") + " " + m[2]
+			if m[3] != "" {
+				line += " " + m[3]
+			}
+			inRecipe = false
+		case makeRule.MatchString(line):
+			line = trimTrailing(line)
+			if m := makeRule.FindStringSubmatch(line); m[2] != "" {
+				helps[len(lines)] = [2]string{strings.TrimRight(m[1], " \t"), m[2]}
+			}
+			inRecipe = true
+		default:
+			inRecipe = false
+		}
+		lines = append(lines, line)
+	}
+
+	width := 0
+	for _, help := range helps {
+		if n := len(help[0]); n > width && n <= helpColumn {
+			width = n
+		}
+	}
+	for i, help := range helps {
+		lines[i] = fmt.Sprintf("%-*s ## %s", width, help[0], help[1])
+	}
+
+	// A single declaration is left as written, several are merged into the
+	// first.
+	if phonyCount > 1 {
+		lines[phonyAt] = ".PHONY: " + strings.Join(phony, " ")
+	}
+	return []byte(collapseBlankLines(lines) + "\n")
+}
+
+// trimTrailing strips the trailing whitespace of line, unless that would leave
+// it ending with a backslash, continuing it onto the next line.
+func trimTrailing(line string) string {
+	trimmed := strings.TrimRight(line, " \t")
+	if strings.HasSuffix(trimmed, "\\") && !strings.HasSuffix(line, "\\") {
+		return line
+	}
+	return trimmed
+}
+
+// collapseBlankLines joins lines, dropping leading blank lines and collapsing
+// runs of blank lines into one.
+func collapseBlankLines(lines []string) string {
+	var kept []string
+	for _, line := range lines {
+		if line == "" && (len(kept) == 0 || kept[len(kept)-1] == "") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimRight(strings.Join(kept, "\n"), "\n")
+}
@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestFormatMakefile(t *testing.T) {
+	for _, c := range []struct {
+		name, in, want string
+	}{
+		{
+			name: "formatted",
+			in:   "GO := go\n\n.PHONY: build\nbuild: ## Builds\n\t$(GO) build ./...\n",
+			want: "GO := go\n\n.PHONY: build\nbuild: ## Builds\n\t$(GO) build ./...\n",
+		},
+		{
+			name: "recipe indented with spaces",
+			in:   "build:\n    go build ./...\n  go vet ./...\n",
+			want: "build:\n\tgo build ./...\n\tgo vet ./...\n",
+		},
+		{
+			name: "assignments",
+			in:   "GO:=go\nexport  CGO_ENABLED?=0\nFLAGS +=-v\nEMPTY=\n",
+			want: "GO := go\nexport CGO_ENABLED ?= 0\nFLAGS += -v\nEMPTY =\n",
+		},
+		{
+			name: "help comments",
+			in:   "build: ## Builds\ntest: build ##Tests\nlint:## Lints\n",
+			want: "build:      ## Builds\ntest: build ## Tests\nlint:       ## Lints\n",
+		},
+		{
+			name: "phony declarations",
+			in:   ".PHONY: build\nbuild:\n\tgo build\n\n.PHONY: test build\ntest:\n\tgo test\n",
+			want: ".PHONY: build test\nbuild:\n\tgo build\n\ntest:\n\tgo test\n",
+		},
+		{
+			name: "blank lines",
+			in:   "\n\nbuild:\n\tgo build\n\n\n\ntest:\n\tgo test\n\n\n",
+			want: "build:\n\tgo build\n\ntest:\n\tgo test\n",
+		},
+		{
+			name: "define",
+			in:   "define HELP\n  build:  builds\nendef\n",
+			want: "define HELP\n  build:  builds\nendef\n",
+		},
+		{
+			name: "trailing whitespace",
+			in:   "SEP := , \nbuild: \n\tgo build  \n\techo a\\ \n# builds  \n  \n",
+			want: "SEP := , \nbuild:\n\tgo build\n\techo a\\ \n# builds\n",
+		},
+		{
+			name: "conditionals in recipes",
+			in:   "build:\n  ifeq ($(OS),Windows_NT)\n    go build -o app.exe\n  else\n    go build\n  endif\n",
+			want: "build:\n  ifeq ($(OS),Windows_NT)\n\tgo build -o app.exe\n  else\n\tgo build\n  endif\n",
+		},
+		{
+			name: "continued lines",
+			in:   "build:\n\tgo build \\\n    -v\n",
+			want: "build:\n\tgo build \\\n    -v\n",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := string(formatMakefile([]byte(c.in))); got != c.want {
+				t.Errorf("formatMakefile(%q) =\n%s\nwant\n%s", c.in, got, c.want)
+			}
+			if got := string(formatMakefile([]byte(c.want))); got != c.want {
+				t.Errorf("formatting again changes the Makefile to\n%s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// preserveMarker ends the managed part of a file. What follows it belongs to
+// the user and is kept when the project is regenerated with -force.
+const preserveMarker = "maker:preserve — edits below this line are kept when maker regenerates this file"
+
+// markedFiles are the comment prefixes of the generated files carrying the
+// ownership markers, by base name. Files whose syntax has no comments, or
+// that other tools rewrite, such as go.mod, carry none.
+var markedFiles = map[string]string{
+	"Makefile":   "#",
+	".gitignore": "#",
+	toolsFile:    "#",
+}
+
+// commentPrefix returns the comment prefix of the generated file at the slash
+// separated path, and whether it carries the ownership markers.
+func commentPrefix(name string) (string, bool) {
+	prefix, ok := markedFiles[path.Base(name)]
+	return prefix, ok
+}
+
+// addMarkers returns the rendered file at name between the header stating
+// that maker manages it and the marker after which edits are preserved.
+func addMarkers(name string, contents []byte) []byte {
+	prefix, ok := commentPrefix(name)
+	if !ok {
+		return contents
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s Code generated by maker %s; managed block — edits below markers are preserved.\n\n", prefix, Version)
+	b.Write(contents)
+	if !bytes.HasSuffix(contents, []byte("\n")) {
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "\n%s %s\n", prefix, preserveMarker)
+	return b.Bytes()
+}
+
+// splitMarkers splits the file at name into its managed part, up to and
+// including the preserve marker, and the user part after it. A file without
+// the marker is managed whole.
+func splitMarkers(name string, contents []byte) (managed, user []byte) {
+	prefix, ok := commentPrefix(name)
+	if !ok {
+		return contents, nil
+	}
+	marker := []byte(prefix + " " + preserveMarker + "\n")
+	i := bytes.Index(contents, marker)
+	if i < 0 {
+		return contents, nil
+	}
+	end := i + len(marker)
+	return contents[:end], contents[end:]
+}
+
+// preservedEdits returns the user part of the existing file at name in dir,
+// which regenerating it keeps.
+func preservedEdits(dir, name string) ([]byte, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, user := splitMarkers(name, contents)
+	if len(strings.TrimSpace(string(user))) == 0 {
+		return nil, nil
+	}
+	return user, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarkers(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		marked bool
+	}{
+		{"Makefile", true},
+		{"services/api/Makefile", true},
+		{".gitignore", true},
+		{toolsFile, true},
+		{"go.mod", false},
+		{"main.go", false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			contents := []byte("generated\n")
+			marked := addMarkers(c.name, contents)
+			if !c.marked {
+				if string(marked) != string(contents) {
+					t.Fatalf("addMarkers changed an unmarked file to %q", marked)
+				}
+				return
+			}
+			if !strings.HasPrefix(string(marked), "# Code generated by maker") {
+				t.Errorf("addMarkers gives %q, want the generated header first", marked)
+			}
+			if !strings.HasSuffix(string(marked), "# "+preserveMarker+"\n") {
+				t.Errorf("addMarkers gives %q, want the preserve marker last", marked)
+			}
+
+			edited := append(append([]byte{}, marked...), "mine\n"...)
+			managed, user := splitMarkers(c.name, edited)
+			if string(managed) != string(marked) || string(user) != "mine\n" {
+				t.Errorf("splitMarkers = %q, %q, want %q, %q", managed, user, marked, "mine\n")
+			}
+		})
+	}
+}
+
+func TestSplitMarkersUnmarked(t *testing.T) {
+	contents := []byte("all:\n\t@echo hand written\n")
+	managed, user := splitMarkers("Makefile", contents)
+	if string(managed) != string(contents) || user != nil {
+		t.Errorf("splitMarkers = %q, %q, want the whole file managed", managed, user)
+	}
+}
+
+func TestPreservedEdits(t *testing.T) {
+	dir := t.TempDir()
+	for _, c := range []struct {
+		name, contents, want string
+	}{
+		{"Makefile", string(addMarkers("Makefile", []byte("all:\n"))) + "\nlocal:\n\t@echo mine\n", "\nlocal:\n\t@echo mine\n"},
+		{".gitignore", string(addMarkers(".gitignore", []byte("bin/\n"))) + "\n\n", ""},
+		{"go.mod", "module example.com/m\n", ""},
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, c.name), []byte(c.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := preservedEdits(dir, c.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != c.want {
+			t.Errorf("preservedEdits(%s) = %q, want %q", c.name, got, c.want)
+		}
+	}
+	if got, err := preservedEdits(dir, "missing/Makefile"); err != nil || got != nil {
+		t.Errorf("preservedEdits of a missing file = %q, %v, want nothing", got, err)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// strategies are how regenerating a project with -force resolves a managed
+// file modified since it was generated: keeping the modified file, taking the
+// new one, merging both or asking which for each file.
+var strategies = []string{"ask", "mine", "new", "merge"}
+
+// conflicted are the files the last generation merged with conflicts.
+var conflicted []string
+
+// answers reads the answers to the questions of the ask strategy.
+var answers = bufio.NewReader(os.Stdin)
+
+// hunk replaces the lines from up to to of a file with lines.
+type hunk struct {
+	from, to int
+	lines    []string
+}
+
+// resolveModified returns the contents to regenerate the managed part of the
+// file at name in dir with when the existing file was modified since it was
+// generated, as the previous lock records it, resolved with the strategy of
+// data. The file is kept as it is when kept returns the checksum to record
+// for it instead.
+func resolveModified(ctx context.Context, dir, name string, managed []byte, data map[string]interface{}, previous *lock) (contents []byte, kept string, err error) {
+	sum := ""
+	for _, f := range previous.Files {
+		if f.Path == name {
+			sum = f.SHA256
+		}
+	}
+	existing, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) || sum == "" {
+		return managed, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	mine, _ := splitMarkers(name, existing)
+	if sha256Sum(mine) == sum || string(mine) == string(managed) {
+		return managed, "", nil
+	}
+
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	strategy, _ := data["strategy"].(string)
+	// Without a terminal to ask on, the modified file is kept rather than
+	// losing the edits, and reported as skipped.
+	if strategy == "" {
+		strategy = "mine"
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			strategy = "ask"
+		}
+	}
+	// A dry run plans the new file rather than asking.
+	if strategy == "ask" && dryRun {
+		strategy = "new"
+	}
+	if strategy == "ask" {
+		answer, err := ask(name+" was modified since it was generated", "keep [m]ine, take [n]ew or merge [h]unks", "m", "n", "h")
+		if err != nil {
+			return nil, "", err
+		}
+		strategy = map[string]string{"m": "mine", "n": "new", "h": "hunks"}[answer]
+	}
+
+	base := generatedBase(ctx, dir, name, sum)
+	switch strategy {
+	case "mine":
+		report(skipped, path+" was modified since it was generated, kept as it is")
+		return nil, sum, nil
+	case "merge":
+		conflicts := 0
+		lines := mergeLines(base, splitLines(mine), splitLines(managed), func(ours, theirs []string) []string {
+			conflicts++
+			return conflictLines(ours, theirs)
+		})
+		if conflicts > 0 {
+			conflicted = append(conflicted, path)
+		}
+		return []byte(strings.Join(lines, "")), "", nil
+	case "hunks":
+		var errs []error
+		lines := mergeLines(base, splitLines(mine), splitLines(managed), func(ours, theirs []string) []string {
+			fmt.Println(strings.Join(conflictLines(ours, theirs), ""))
+			answer, err := ask("", "keep [m]ine or take [n]ew", "m", "n")
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if answer == "n" {
+				return theirs
+			}
+			return ours
+		})
+		if len(errs) > 0 {
+			return nil, "", errs[0]
+		}
+		return []byte(strings.Join(lines, "")), "", nil
+	}
+	return managed, "", nil
+}
+
+// ask prints question and prompt and returns the first of choices the answer
+// starts with, asking again until it starts with one. At the end of the input
+// the answer is m, keeping the modified file as a non-interactive run does.
+func ask(question, prompt string, choices ...string) (string, error) {
+	if question != "" {
+		fmt.Println(question)
+	}
+	for {
+		fmt.Printf("  %s? ", prompt)
+		line, err := answers.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		for _, choice := range choices {
+			if answer != "" && strings.HasPrefix(choice, answer[:1]) {
+				return choice, nil
+			}
+		}
+		if err == io.EOF {
+			fmt.Println()
+			return "m", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("no answer to %q: %v", prompt, err)
+		}
+	}
+}
+
+// generatedBase returns the lines of the managed part of the file at name as
+// it was generated, from the last commit of the lock file in the git
+// repository of dir, or nil when that commit holds no file with the checksum
+// sum the lock records.
+func generatedBase(ctx context.Context, dir, name, sum string) []string {
+	rev, err := queryCommand(ctx, dir, "git", "log", "-1", "--format=%H", "--", lockFile)
+	if rev = strings.TrimSpace(rev); err != nil || rev == "" {
+		return nil
+	}
+	contents, err := queryCommand(ctx, dir, "git", "show", rev+":./"+name)
+	if err != nil {
+		return nil
+	}
+	managed, _ := splitMarkers(name, []byte(contents))
+	if sha256Sum(managed) != sum {
+		return nil
+	}
+	return append([]string{}, splitLines(managed)...)
+}
+
+// mergeLines merges the changes from base to mine and to theirs, passing the
+// lines of both sides of each overlapping change to resolve. Without a base,
+// every change between mine and theirs is passed to resolve.
+func mergeLines(base, mine, theirs []string, resolve func(mine, theirs []string) []string) []string {
+	if base == nil {
+		var out []string
+		pos := 0
+		for _, h := range diffLines(mine, theirs) {
+			out = append(out, mine[pos:h.from]...)
+			out = append(out, resolve(mine[h.from:h.to], h.lines)...)
+			pos = h.to
+		}
+		return append(out, mine[pos:]...)
+	}
+
+	ours, others := diffLines(base, mine), diffLines(base, theirs)
+	var out []string
+	pos := 0
+	for len(ours) > 0 || len(others) > 0 {
+		// The next change is grown to cover the changes of either side that
+		// overlap or touch it.
+		var lo int
+		switch {
+		case len(others) == 0 || len(ours) > 0 && ours[0].from <= others[0].from:
+			lo = ours[0].from
+		default:
+			lo = others[0].from
+		}
+		hi := lo
+		var a, b []hunk
+		for grown := true; grown; {
+			grown = false
+			if len(ours) > 0 && ours[0].from <= hi {
+				a, ours, grown = append(a, ours[0]), ours[1:], true
+				if a[len(a)-1].to > hi {
+					hi = a[len(a)-1].to
+				}
+			}
+			if len(others) > 0 && others[0].from <= hi {
+				b, others, grown = append(b, others[0]), others[1:], true
+				if b[len(b)-1].to > hi {
+					hi = b[len(b)-1].to
+				}
+			}
+		}
+		apply := func(hunks []hunk) []string {
+			var lines []string
+			p := lo
+			for _, h := range hunks {
+				lines = append(lines, base[p:h.from]...)
+				lines = append(lines, h.lines...)
+				p = h.to
+			}
+			return append(lines, base[p:hi]...)
+		}
+
+		out = append(out, base[pos:lo]...)
+		switch mine, theirs := apply(a), apply(b); {
+		case len(b) == 0:
+			out = append(out, mine...)
+		case len(a) == 0 || strings.Join(mine, "") == strings.Join(theirs, ""):
+			out = append(out, theirs...)
+		default:
+			out = append(out, resolve(mine, theirs)...)
+		}
+		pos = hi
+	}
+	return append(out, base[pos:]...)
+}
+
+// conflictLines marks the lines of mine and theirs as a conflict, as git marks
+// the conflicts of a merge.
+func conflictLines(mine, theirs []string) []string {
+	lines := []string{"<<<<<<< mine\n"}
+	lines = append(lines, mine...)
+	lines = append(lines, "=======\n")
+	lines = append(lines, theirs...)
+	return append(lines, ">>>>>>> maker\n")
+}
+
+// diffLines returns the hunks turning the lines a into the lines b, from the
+// longest common subsequence of the lines between their common prefix and
+// suffix.
+func diffLines(a, b []string) []hunk {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	x, y := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var hunks []hunk
+	var h *hunk
+	for i, j := 0, 0; i < len(x) || j < len(y); {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			if h != nil {
+				hunks, h = append(hunks, *h), nil
+			}
+			i, j = i+1, j+1
+		case j < len(y) && (i == len(x) || lcs[i][j+1] >= lcs[i+1][j]):
+			if h == nil {
+				h = &hunk{from: prefix + i, to: prefix + i}
+			}
+			h.lines = append(h.lines, y[j])
+			j++
+		default:
+			if h == nil {
+				h = &hunk{from: prefix + i, to: prefix + i}
+			}
+			i++
+			h.to = prefix + i
+		}
+	}
+	if h != nil {
+		hunks = append(hunks, *h)
+	}
+	return hunks
+}
+
+// splitLines splits contents into its lines, each ending in its newline.
+func splitLines(contents []byte) []string {
+	lines := strings.SplitAfter(string(contents), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		a, b  string
+		hunks int
+	}{
+		{"equal", "a\nb\nc\n", "a\nb\nc\n", 0},
+		{"insert", "a\nc\n", "a\nb\nc\n", 1},
+		{"delete", "a\nb\nc\n", "a\nc\n", 1},
+		{"replace", "a\nb\nc\n", "a\nx\nc\n", 1},
+		{"from empty", "", "a\nb\n", 1},
+		{"to empty", "a\nb\n", "", 1},
+		{"apart", "a\nb\nc\nd\ne\n", "x\nb\nc\nd\ny\n", 2},
+		{"moved", "a\nb\nc\n", "c\na\nb\n", 2},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			a, b := splitLines([]byte(c.a)), splitLines([]byte(c.b))
+			hunks := diffLines(a, b)
+			if len(hunks) != c.hunks {
+				t.Errorf("got %d hunks, want %d: %v", len(hunks), c.hunks, hunks)
+			}
+			// Applying the hunks to a must give b.
+			var out []string
+			pos := 0
+			for _, h := range hunks {
+				out = append(out, a[pos:h.from]...)
+				out = append(out, h.lines...)
+				pos = h.to
+			}
+			out = append(out, a[pos:]...)
+			if got := strings.Join(out, ""); got != c.b {
+				t.Errorf("applying the hunks gives %q, want %q", got, c.b)
+			}
+		})
+	}
+}
+
+func TestMergeLines(t *testing.T) {
+	conflict := func(mine, theirs []string) []string { return conflictLines(mine, theirs) }
+	for _, c := range []struct {
+		name               string
+		base, mine, theirs string
+		noBase             bool
+		want               string
+	}{
+		{name: "unchanged", base: "a\nb\n", mine: "a\nb\n", theirs: "a\nb\n", want: "a\nb\n"},
+		{name: "mine only", base: "a\nb\n", mine: "a\nb\nmine\n", theirs: "a\nb\n", want: "a\nb\nmine\n"},
+		{name: "theirs only", base: "a\nb\n", mine: "a\nb\n", theirs: "x\na\nb\n", want: "x\na\nb\n"},
+		{name: "both apart", base: "a\nb\nc\nd\n", mine: "a\nmine\nb\nc\nd\n", theirs: "a\nb\nc\nd\ntheirs\n", want: "a\nmine\nb\nc\nd\ntheirs\n"},
+		{name: "same change", base: "a\nb\n", mine: "a\nx\n", theirs: "a\nx\n", want: "a\nx\n"},
+		{name: "conflict", base: "a\nb\nc\n", mine: "a\nmine\nc\n", theirs: "a\ntheirs\nc\n", want: "a\n<<<<<<< mine\nmine\n=======\ntheirs\n>>>>>>> maker\nc\n"},
+		{name: "touching", base: "a\nb\nc\n", mine: "a\nB\nc\n", theirs: "a\nb\nC\n", want: "a\n<<<<<<< mine\nB\nc\n=======\nb\nC\n>>>>>>> maker\n"},
+		{name: "no base", noBase: true, mine: "a\nmine\nc\n", theirs: "a\ntheirs\nc\n", want: "a\n<<<<<<< mine\nmine\n=======\ntheirs\n>>>>>>> maker\nc\n"},
+		{name: "no base equal", noBase: true, mine: "a\nb\n", theirs: "a\nb\n", want: "a\nb\n"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var base []string
+			if !c.noBase {
+				base = append([]string{}, splitLines([]byte(c.base))...)
+			}
+			got := strings.Join(mergeLines(base, splitLines([]byte(c.mine)), splitLines([]byte(c.theirs)), conflict), "")
+			if got != c.want {
+				t.Errorf("got\n%s\nwant\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestResolveModified(t *testing.T) {
+	dir := t.TempDir()
+	generated := []byte("generated\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	previous := &lock{Files: []lockedFile{{"notes.txt", sha256Sum(generated)}}}
+	for _, c := range []struct {
+		strategy, want string
+		kept           bool
+	}{
+		// The tests run without a terminal, like a CI job.
+		{"", "", true},
+		{"mine", "", true},
+		{"new", "regenerated\n", false},
+	} {
+		t.Run(c.strategy, func(t *testing.T) {
+			data := map[string]interface{}{"strategy": c.strategy}
+			contents, kept, err := resolveModified(context.Background(), dir, "notes.txt", []byte("regenerated\n"), data, previous)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(contents) != c.want || (kept != "") != c.kept {
+				t.Errorf("resolveModified with %q = %q, kept %q, want %q, kept %v", c.strategy, contents, kept, c.want, c.kept)
+			}
+		})
+	}
+}
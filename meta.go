@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// defaultPort is the port an http project listens on unless -port is set.
+const defaultPort = 8080
+
+// projectMeta are the values several files of a project repeat, such as the
+// port that main.go listens on, the Dockerfile exposes and the systemd unit
+// sets. The templates read them from .meta so each is defined once.
+type projectMeta struct {
+	Name        string
+	Module      string
+	Description string
+	Keywords    []string
+	// Homepage defaults to the web page of the repository.
+	Homepage string
+	Port     int
+	// Binaries are the binaries built from cmd/NAME with -binaries, empty
+	// for the one built from main.go.
+	Binaries []string
+	// Registry prefixes the image name, as in ghcr.io/team.
+	Registry string
+	Team     string
+	// Package is the name of the package of a library, the name in lower
+	// case without separators as Go package names are.
+	Package string
+	// Separator joins the image name with the name of a binary, as the
+	// naming convention joins words: - by default, none for camel case.
+	Separator string
+}
+
+// binaryName matches the name of a binary of -binaries.
+var binaryName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// namingConventions are the conventions -naming names the project, its
+// binaries, their directories and images in.
+var namingConventions = []string{"kebab", "snake", "camel"}
+
+// applyNaming returns name in the naming convention, splitting it into words
+// at dashes, underscores, spaces and the upper case letters following a lower
+// case one or a digit.
+func applyNaming(name, convention string) string {
+	var words []string
+	word := ""
+	for i, r := range name {
+		switch {
+		case r == '-' || r == '_' || r == ' ':
+			words, word = append(words, word), ""
+			continue
+		case unicode.IsUpper(r) && i > 0 && word != "" && !unicode.IsUpper(rune(name[i-1])):
+			words, word = append(words, word), ""
+		}
+		word += string(unicode.ToLower(r))
+	}
+	words = append(words, word)
+
+	var out []string
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		if convention == "camel" && len(out) > 0 {
+			w = strings.ToUpper(w[:1]) + w[1:]
+		}
+		out = append(out, w)
+	}
+	switch convention {
+	case "kebab":
+		return strings.Join(out, "-")
+	case "snake":
+		return strings.Join(out, "_")
+	}
+	return strings.Join(out, "")
+}
+
+// BinaryNames are the names of the binaries of the project.
+func (m projectMeta) BinaryNames() []string {
+	if len(m.Binaries) == 0 {
+		return []string{m.Name}
+	}
+	return m.Binaries
+}
+
+// Main is the path of the main package of a binary of the project.
+func (m projectMeta) Main(binary string) string {
+	if len(m.Binaries) == 0 {
+		return "."
+	}
+	return "./cmd/" + binary
+}
+
+// Image is the name of the docker image, in the Registry when one is set.
+// Image names are lower case, so a camel case name is lowered.
+func (m projectMeta) Image() string {
+	if m.Registry == "" {
+		return strings.ToLower(m.Name)
+	}
+	return m.Registry + "/" + strings.ToLower(m.Name)
+}
+
+// setMeta sets the meta of the template data from its other keys.
+func setMeta(data map[string]interface{}) error {
+	meta := projectMeta{Port: defaultPort}
+	meta.Name, _ = data["name"].(string)
+	meta.Package = strings.ToLower(applyNaming(meta.Name, "camel"))
+	switch data["naming"] {
+	case "snake":
+		meta.Separator = "_"
+	case "camel":
+	default:
+		meta.Separator = "-"
+	}
+	meta.Module, _ = data["module"].(string)
+	meta.Description, _ = data["description"].(string)
+	meta.Homepage, _ = data["homepage"].(string)
+	if meta.Homepage != "" && !strings.HasPrefix(meta.Homepage, "https://") && !strings.HasPrefix(meta.Homepage, "http://") {
+		return fmt.Errorf("invalid homepage %q, expected an http or https URL", meta.Homepage)
+	}
+	if repo, _ := data["vcs"].(vcsRepo); meta.Homepage == "" && repo.Path != "" {
+		meta.Homepage = repo.URL()
+	}
+	keywords, _ := data["keywords"].(string)
+	for _, keyword := range strings.Split(keywords, ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			meta.Keywords = append(meta.Keywords, keyword)
+		}
+	}
+	binaries, _ := data["binaries"].(string)
+	for _, binary := range strings.Split(binaries, ",") {
+		if binary = strings.TrimSpace(binary); binary == "" {
+			continue
+		}
+		if !binaryName.MatchString(binary) {
+			return fmt.Errorf("invalid binary name %q, expected letters, digits, - and _", binary)
+		}
+		if !contains(meta.Binaries, binary) {
+			meta.Binaries = append(meta.Binaries, binary)
+		}
+	}
+	meta.Registry, _ = data["registry"].(string)
+	meta.Team, _ = data["team"].(string)
+	if port, ok := data["port"].(int); ok && port != 0 {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %d, expected 1 to 65535", port)
+		}
+		meta.Port = port
+	}
+	if meta.Description == "" {
+		meta.Description = meta.Name
+	}
+	data["meta"] = meta
+	return nil
+}
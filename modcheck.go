@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// moduleProxyTimeout bounds how long the module proxy is asked whether a
+// module path is taken.
+const moduleProxyTimeout = 5 * time.Second
+
+// warnTakenModule warns when the module path of a new project already names a
+// module in the module cache or on the module proxy, which the project would
+// collide with. The proxy is not asked with -offline or for the private
+// modules of GOPRIVATE and GONOPROXY.
+func warnTakenModule(ctx context.Context, module string) {
+	if module == "" {
+		return
+	}
+	escaped := escapeModulePath(module)
+	if exists(filepath.Join(moduleCacheDir(), "cache", "download", filepath.FromSlash(escaped), "@v")) {
+		fmt.Fprintf(os.Stderr, "warning: %s is already in the module cache, choose another module path unless this project is its new home\n", module)
+		return
+	}
+	if offline || privateModule(module) {
+		return
+	}
+	proxy := moduleProxy()
+	if proxy == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, moduleProxyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, proxy+"/"+escaped+"/@latest", nil)
+	if err != nil {
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	// The proxy answers 404 or 410 for a path it cannot resolve.
+	if resp.StatusCode == http.StatusOK {
+		fmt.Fprintf(os.Stderr, "warning: %s is already published on %s, choose another module path unless this project is its new home\n", module, proxy)
+	}
+}
+
+// escapeModulePath escapes a module path like the module cache and proxy do,
+// writing each upper-case letter as an exclamation mark followed by the
+// letter in lower case.
+func escapeModulePath(module string) string {
+	var b strings.Builder
+	for _, r := range module {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// moduleCacheDir returns the module cache of the go command, from GOMODCACHE
+// or the first entry of GOPATH.
+func moduleCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(os.Getenv("GOPATH"))
+	if len(gopath) > 0 && gopath[0] != "" {
+		return filepath.Join(gopath[0], "pkg", "mod")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "go", "pkg", "mod")
+}
+
+// moduleProxy returns the first https proxy of GOPROXY, or an empty string
+// when it lists none before off or direct.
+func moduleProxy() string {
+	proxies := os.Getenv("GOPROXY")
+	if proxies == "" {
+		proxies = "https://proxy.golang.org,direct"
+	}
+	for _, proxy := range strings.FieldsFunc(proxies, func(r rune) bool { return r == ',' || r == '|' }) {
+		if !strings.HasPrefix(proxy, "https://") {
+			return ""
+		}
+		return strings.TrimSuffix(proxy, "/")
+	}
+	return ""
+}
+
+// privateModule reports whether a module path matches the patterns of
+// GOPRIVATE or GONOPROXY, which the go command keeps from the proxy too.
+func privateModule(module string) bool {
+	patterns := os.Getenv("GONOPROXY")
+	if patterns == "" {
+		patterns = os.Getenv("GOPRIVATE")
+	}
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		// A pattern matches a path prefix with as many elements as it has.
+		n := strings.Count(pattern, "/") + 1
+		parts := strings.Split(module, "/")
+		if len(parts) < n {
+			continue
+		}
+		if ok, _ := path.Match(pattern, strings.Join(parts[:n], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
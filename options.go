@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// option describes an option of maker as wrappers, web UIs and the serve page
+// read it, built from the config schema, the flags and the features so that
+// none of them repeats what an option is.
+type option struct {
+	Name string `json:"name"`
+	// Flag is the command line flag setting the option, empty for the
+	// options only read from config files.
+	Flag         string      `json:"flag,omitempty"`
+	Type         string      `json:"type"`
+	Description  string      `json:"description"`
+	Default      interface{} `json:"default"`
+	Enum         []string    `json:"enum,omitempty"`
+	Feature      bool        `json:"feature"`
+	Experimental bool        `json:"experimental,omitempty"`
+	Requires     []string    `json:"requires,omitempty"`
+	Conflicts    []string    `json:"conflicts,omitempty"`
+	// ConsumedBy are the features whose files or Makefile sections read the
+	// option.
+	ConsumedBy []string `json:"consumedBy,omitempty"`
+}
+
+// options returns every option of maker in the order of the config schema.
+// Defining the flags anew resets the variables of those changing how maker
+// runs, so it is called before any flags are parsed.
+func options() []option {
+	flags := flag.NewFlagSet("maker", flag.ContinueOnError)
+	defineFlags(flags)
+	flagNames := map[string]string{}
+	flags.VisitAll(func(f *flag.Flag) {
+		flagNames[configKey(f.Name)] = f.Name
+	})
+
+	defaults := templateData("project")
+	consumers := optionConsumers()
+	s := configSchema()
+	var out []option
+	for _, key := range schemaOrder() {
+		property := s.Properties[key]
+		o := option{
+			Name:        key,
+			Flag:        flagNames[key],
+			Type:        property.Type,
+			Description: property.Description,
+			Enum:        property.Enum,
+			ConsumedBy:  consumers[key],
+		}
+		if value, ok := defaults[key]; ok {
+			o.Default = value
+		} else if f := flags.Lookup(o.Flag); f != nil {
+			o.Default = f.Value.(flag.Getter).Get()
+		}
+		if f, ok := lookupFeature(key); ok {
+			o.Feature = true
+			o.Experimental = f.experimental
+			o.Requires = f.requires
+			o.Conflicts = f.conflicts
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+// schemaOrder returns the properties of the config schema in the order
+// maker.schema.json lists them, which groups related options.
+func schemaOrder() []string {
+	var doc struct {
+		Properties json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(schemaJSON, &doc); err != nil {
+		panic(err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(doc.Properties)))
+	dec.Token()
+	var keys []string
+	for dec.More() {
+		key, _ := dec.Token()
+		keys = append(keys, key.(string))
+		var skip json.RawMessage
+		dec.Decode(&skip)
+	}
+	return keys
+}
+
+// derivedKeys are the keys of the template data maker derives from other
+// options rather than reads from the config, with the options they come from.
+var derivedKeys = map[string][]string{
+	"module":   {"mod", "modulePrefix", "github"},
+	"vcs":      {"module", "vcsHost", "github"},
+	"pipeline": {"vcs", "ciProvider"},
+	"meta":     {"module", "vcs", "description", "homepage", "keywords", "binaries", "registry", "team", "port"},
+	"catalog":  {"locale"},
+}
+
+// optionConsumers maps every option to the features reading it: those whose
+// file templates use it and those whose Makefile sections use it within
+// their {{if .FEATURE}} conditional.
+func optionConsumers() map[string][]string {
+	consumers := map[string][]string{}
+	var add func(key, name string)
+	add = func(key, name string) {
+		for _, source := range derivedKeys[key] {
+			add(source, name)
+		}
+		if key != name && !contains(consumers[key], name) {
+			consumers[key] = append(consumers[key], name)
+		}
+	}
+	funcs := templateFuncs(context.Background())
+	for _, f := range features {
+		for _, file := range f.files {
+			for _, text := range []string{file.path, fileTemplates[file.template]} {
+				templ, err := template.New("").Funcs(funcs).Parse(text)
+				if err != nil {
+					continue
+				}
+				for _, t := range templ.Templates() {
+					walkFields(t.Root, nil, true, func(key string, _ []string) { add(key, f.name) })
+				}
+			}
+		}
+	}
+	templ, err := template.New("makefile").Funcs(funcs).Parse(makefileTemplate)
+	if err != nil {
+		panic(err)
+	}
+	for _, t := range templ.Templates() {
+		walkFields(t.Root, nil, true, func(key string, guards []string) {
+			for _, name := range guards {
+				add(key, name)
+			}
+		})
+	}
+	return consumers
+}
+
+// walkFields calls visit with every key of the template data n reads, along
+// with the features of the conditionals enclosing it. top is set while dot is
+// still the template data.
+func walkFields(n parse.Node, guards []string, top bool, visit func(key string, guards []string)) {
+	var pipe func(p *parse.PipeNode, top bool)
+	var arg func(n parse.Node, top bool)
+	pipe = func(p *parse.PipeNode, top bool) {
+		if p == nil {
+			return
+		}
+		for _, cmd := range p.Cmds {
+			for _, a := range cmd.Args {
+				arg(a, top)
+			}
+		}
+	}
+	arg = func(n parse.Node, top bool) {
+		switch n := n.(type) {
+		case *parse.FieldNode:
+			if top {
+				visit(n.Ident[0], guards)
+			}
+		case *parse.VariableNode:
+			if n.Ident[0] == "$" && len(n.Ident) > 1 {
+				visit(n.Ident[1], guards)
+			}
+		case *parse.ChainNode:
+			arg(n.Node, top)
+		case *parse.PipeNode:
+			pipe(n, top)
+		}
+	}
+
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkFields(child, guards, top, visit)
+		}
+	case *parse.ActionNode:
+		pipe(n.Pipe, top)
+	case *parse.TemplateNode:
+		pipe(n.Pipe, top)
+	case *parse.IfNode:
+		pipe(n.Pipe, top)
+		inner := guards
+		for _, cmd := range n.Pipe.Cmds {
+			for _, a := range cmd.Args {
+				if f, ok := a.(*parse.FieldNode); ok && top && len(f.Ident) == 1 && isOption(f.Ident[0]) {
+					inner = append(append([]string{}, inner...), f.Ident[0])
+				}
+			}
+		}
+		walkFields(n.List, inner, top, visit)
+		walkFields(n.ElseList, guards, top, visit)
+	case *parse.RangeNode:
+		pipe(n.Pipe, top)
+		walkFields(n.List, guards, false, visit)
+		walkFields(n.ElseList, guards, top, visit)
+	case *parse.WithNode:
+		pipe(n.Pipe, top)
+		walkFields(n.List, guards, false, visit)
+		walkFields(n.ElseList, guards, top, visit)
+	}
+}
+
+// schemaCommand runs maker schema, printing the options as a table or, with
+// -json, as a JSON array.
+func schemaCommand(args []string) {
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Prints the options as a JSON array")
+	flags.Parse(args)
+	if len(flags.Args()) != 0 {
+		fmt.Println("Expected use: maker schema [-json]")
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(options()); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	for _, o := range options() {
+		fmt.Printf("%-16s %-8s %s\n", o.Name, o.Type, o.Description)
+		if o.Flag != "" {
+			fmt.Printf("%-16s   flag -%s, default %v\n", "", o.Flag, o.Default)
+		}
+		if len(o.Enum) > 0 {
+			fmt.Printf("%-16s   one of %s\n", "", strings.Join(o.Enum, ", "))
+		}
+		if len(o.Requires) > 0 {
+			fmt.Printf("%-16s   requires %s\n", "", strings.Join(o.Requires, ", "))
+		}
+		if len(o.Conflicts) > 0 {
+			fmt.Printf("%-16s   conflicts with %s\n", "", strings.Join(o.Conflicts, ", "))
+		}
+		if len(o.ConsumedBy) > 0 {
+			fmt.Printf("%-16s   read by %s\n", "", strings.Join(o.ConsumedBy, ", "))
+		}
+	}
+}
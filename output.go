@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// plain disables the colors and glyphs of the generator output, for CI logs.
+var plain bool
+
+// status is the outcome of writing or removing a project file, or of a doctor
+// check.
+type status struct {
+	glyph string
+	color string
+	word  string
+}
+
+var (
+	passed  = status{"✓", "\033[32m", "ok"}
+	created = status{"+", "\033[32m", "created"}
+	updated = status{"~", "\033[32m", "updated"}
+	failed  = status{"✗", "\033[31m", "failed"}
+	removed = status{"✓", "\033[32m", "removed"}
+	skipped = status{"!", "\033[33m", "skipped"}
+	planned = status{"+", "\033[36m", "would create"}
+	// wouldUpdate is planned for a file that exists.
+	wouldUpdate = status{"~", "\033[36m", "would update"}
+	// autoEnabled is a feature enabled because another requires it.
+	autoEnabled = status{"+", "\033[36m", "enabled"}
+	// autoDisabled is a feature disabled because a policy forbids it.
+	autoDisabled = status{"-", "\033[33m", "disabled"}
+)
+
+// report prints the status of the file at path. Glyphs are colored only when
+// stdout is a terminal and NO_COLOR is unset.
+func report(s status, path string) {
+	switch {
+	case plain:
+		fmt.Printf("%s %s\n", s.word, path)
+	case colored():
+		fmt.Printf("%s%s\033[0m %s\n", s.color, s.glyph, path)
+	default:
+		fmt.Printf("%s %s\n", s.glyph, path)
+	}
+}
+
+// colored reports whether the output may use escape sequences.
+func colored() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// summary prints the options the project in dir was generated with and the
+// commands to run next.
+func summary(dir string, data map[string]interface{}) {
+	var enabled []string
+	for _, f := range enabledFeatures(data) {
+		enabled = append(enabled, f.name)
+	}
+	features := "no options"
+	if len(enabled) > 0 {
+		features = strings.Join(enabled, ", ")
+	}
+	fmt.Printf("\nGenerated %s %s project with %s.\n\nNext steps:\n", dir, data["type"], features)
+
+	steps := []string{"cd " + dir}
+	if data["type"] == "monorepo" {
+		steps = append(steps, "maker add-service NAME")
+	} else {
+		if data["module"] != "" && data["test"] == true && data["assertions"] != "stdlib" {
+			steps = append(steps, "go mod tidy")
+		}
+		steps = append(steps, "make bootstrap")
+		if data["test"] == true {
+			steps = append(steps, "make test")
+		} else {
+			steps = append(steps, "make build")
+		}
+	}
+	remote := "URL"
+	if repo, _ := data["vcs"].(vcsRepo); repo.Path != "" {
+		remote = repo.RemoteURL()
+	}
+	steps = append(steps, "git init && git remote add origin "+remote)
+
+	for _, step := range steps {
+		fmt.Printf("  %s\n", step)
+	}
+}
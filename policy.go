@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// policy is what an organization mandates of the projects generated from the
+// bundle of its preset source: the features they must enable and those they
+// must not. With correct, maker enables and disables them itself and reports
+// what it changed instead of failing.
+type policy struct {
+	Require []string `yaml:"require"`
+	Forbid  []string `yaml:"forbid"`
+	Correct bool     `yaml:"correct"`
+	// source is the URL of the bundle the policy came from.
+	source string
+}
+
+// check returns an error for a policy naming an unknown option or both
+// requiring and forbidding one.
+func (p *policy) check() error {
+	for _, name := range append(append([]string{}, p.Require...), p.Forbid...) {
+		if !isOption(name) {
+			return fmt.Errorf("%s: policy: unknown option %q", p.source, name)
+		}
+	}
+	for _, name := range p.Require {
+		if contains(p.Forbid, name) {
+			return fmt.Errorf("%s: policy: %s is both required and forbidden", p.source, name)
+		}
+	}
+	return nil
+}
+
+// enforcePolicy checks the enabled features of data against the policy of the
+// preset source. A violation fails unless the policy corrects it, in which
+// case the required features are enabled along with those they require, the
+// forbidden ones are disabled and the changes are reported.
+func enforcePolicy(data, config map[string]interface{}) error {
+	p := projectTemplates(data).orgPolicy()
+	if p == nil {
+		return nil
+	}
+	var violations []string
+	for _, name := range p.Require {
+		if data[name] != true {
+			violations = append(violations, fmt.Sprintf("%s is required, enable it with -%s", name, name))
+		}
+	}
+	for _, name := range p.Forbid {
+		if data[name] == true {
+			violations = append(violations, fmt.Sprintf("%s is forbidden, disable it with -%s=false", name, name))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	if !p.Correct {
+		return fmt.Errorf("the policy of %s is violated:\n  %s", p.source, strings.Join(violations, "\n  "))
+	}
+
+	// The forbidden features are disabled as if the config did, so that none
+	// is enabled again for a feature requiring it.
+	forbidden := map[string]interface{}{}
+	for _, name := range p.Forbid {
+		forbidden[name] = false
+		if data[name] == true {
+			data[name] = false
+			if summarize {
+				report(autoDisabled, fmt.Sprintf("%s, which the policy of %s forbids", name, p.source))
+			}
+		}
+	}
+	for _, name := range p.Require {
+		if data[name] != true {
+			data[name] = true
+			if summarize {
+				report(autoEnabled, fmt.Sprintf("%s, which the policy of %s requires", name, p.source))
+			}
+		}
+	}
+	for _, enabled := range enableRequired(data, mergeConfigs(config, forbidden)) {
+		if summarize {
+			report(autoEnabled, enabled)
+		}
+	}
+	return nil
+}
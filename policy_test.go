@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		p    policy
+		err  string
+	}{
+		{"valid", policy{Require: []string{"test"}, Forbid: []string{"docker"}}, ""},
+		{"empty", policy{}, ""},
+		{"unknown required", policy{Require: []string{"tests"}}, `unknown option "tests"`},
+		{"unknown forbidden", policy{Forbid: []string{"dockr"}}, `unknown option "dockr"`},
+		{"required and forbidden", policy{Require: []string{"test"}, Forbid: []string{"test"}}, "test is both required and forbidden"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.p.source = "https://maker.invalid/bundle.yaml"
+			err := c.p.check()
+			if c.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+				t.Fatalf("check = %v, want an error containing %q", err, c.err)
+			}
+		})
+	}
+}
+
+func TestEnforcePolicy(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		p      *policy
+		data   map[string]interface{}
+		config map[string]interface{}
+		want   map[string]interface{}
+		err    string
+	}{
+		{
+			name: "no policy",
+			data: map[string]interface{}{"docker": true},
+			want: map[string]interface{}{"docker": true},
+		},
+		{
+			name: "satisfied",
+			p:    &policy{Require: []string{"test"}, Forbid: []string{"docker"}},
+			data: map[string]interface{}{"test": true},
+			want: map[string]interface{}{"test": true},
+		},
+		{
+			name: "violated",
+			p:    &policy{Require: []string{"test"}, Forbid: []string{"docker"}},
+			data: map[string]interface{}{"docker": true},
+			err:  "test is required, enable it with -test\n  docker is forbidden, disable it with -docker=false",
+		},
+		{
+			name: "corrected",
+			p:    &policy{Require: []string{"cover"}, Forbid: []string{"docker"}, Correct: true},
+			data: map[string]interface{}{"docker": true},
+			want: map[string]interface{}{"docker": false, "cover": true, "test": true},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if c.p != nil {
+				c.p.source = "https://maker.invalid/bundle.yaml"
+				c.data["templateSet"] = &templateSet{policy: c.p}
+			}
+			if c.config == nil {
+				c.config = map[string]interface{}{}
+			}
+			err := enforcePolicy(c.data, c.config)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("enforcePolicy = %v, want an error containing %q", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range c.want {
+				if c.data[name] != want {
+					t.Errorf("%s = %v, want %v", name, c.data[name], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// maxConcurrentFiles bounds how many files are rendered or written at once.
+var maxConcurrentFiles = runtime.NumCPU()
+
+// forEach calls fn with every index below n on at most maxConcurrentFiles
+// goroutines, and returns the error of each call by index.
+func forEach(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	slots := make(chan struct{}, maxConcurrentFiles)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+// filesError lists the files that failed to render or be written.
+type filesError struct {
+	total int
+	errs  []error
+}
+
+func (e *filesError) Error() string {
+	lines := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("%d of %d files failed:\n%s", len(e.errs), e.total, strings.Join(lines, "\n"))
+}
+
+// joinErrors returns nil when none of errs is set, the only error set, or a
+// filesError listing all of them.
+func joinErrors(errs []error) error {
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return failed[0]
+	}
+	return &filesError{len(errs), failed}
+}
@@ -0,0 +1,9 @@
+package main
+
+// presets are named sets of options enabled together with -preset.
+var presets = map[string][]string{
+	"library":   {"library", "test", "cover"},
+	"profiling": {"bench", "cpuProfile", "memProfile"},
+	"quality":   {"shadow", "lintDocker", "deadcode", "spellcheck"},
+	"testing":   {"test", "bench", "cover", "coverHTML", "testRace"},
+}
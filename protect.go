@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// makerIgnoreFile lists, in the .gitignore syntax, the files of a project that
+// maker never overwrites or removes.
+const makerIgnoreFile = ".makerignore"
+
+// protectedPatterns are the files that may hold secrets, which maker never
+// overwrites or removes whatever the ignore files say.
+var protectedPatterns = []string{".env", ".env.*", "*.pem", "*.key", "secrets/"}
+
+// protection matches the files of a project that maker leaves untouched.
+type protection []string
+
+// readProtection returns the protected patterns of the project in dir along
+// with the patterns of its .makerignore and .gitignore files, the ignored
+// files being local work that is not tracked.
+func readProtection(dir string) (protection, error) {
+	patterns := append(protection{}, protectedPatterns...)
+	for _, name := range []string{makerIgnoreFile, ".gitignore"} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			// Negations cannot unprotect a file, so they are skipped.
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+				continue
+			}
+			patterns = append(patterns, line)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return patterns, nil
+}
+
+// protects reports whether the slash separated path, relative to the project
+// directory, matches one of the patterns or is in a directory that does.
+func (p protection) protects(name string) bool {
+	for _, pattern := range p {
+		if matchIgnore(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIgnore reports whether name matches a .gitignore pattern. A pattern
+// ending in a slash only matches directories, and one containing a slash is
+// matched from the project directory rather than against any path element.
+func matchIgnore(pattern, name string) bool {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	parts := strings.Split(name, "/")
+	for i := range parts {
+		if dirOnly && i == len(parts)-1 {
+			break
+		}
+		candidate := parts[i]
+		if anchored {
+			candidate = strings.Join(parts[:i+1], "/")
+		}
+		if ok, _ := path.Match(pattern, candidate); ok {
+			return true
+		}
+	}
+	return false
+}
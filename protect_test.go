@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchIgnore(t *testing.T) {
+	for _, c := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{".env", ".env", true},
+		{".env", "config/.env", true},
+		{".env", ".envrc", false},
+		{".env.*", ".env.local", true},
+		{"*.pem", "certs/server.pem", true},
+		{"*.pem", "certs/server.pem.txt", false},
+		{"secrets/", "secrets/token", true},
+		{"secrets/", "deploy/secrets/token", true},
+		{"secrets/", "secrets", false},
+		{"/bin", "bin/maker", true},
+		{"/bin", "cmd/bin/maker", false},
+		{"docs/*.md", "docs/adr.md", true},
+		{"docs/*.md", "site/docs/adr.md", false},
+		{"node_modules", "web/node_modules/left-pad/index.js", true},
+	} {
+		if got := matchIgnore(c.pattern, c.name); got != c.want {
+			t.Errorf("matchIgnore(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestReadProtection(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		makerIgnoreFile: "# hand written\nlocal/\n\n!keep.pem\n",
+		".gitignore":    "bin/\n*.log\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := readProtection(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		want bool
+	}{
+		{".env", true},
+		{"keep.pem", true},
+		{"secrets/db", true},
+		{"local/notes.txt", true},
+		{"bin/maker", true},
+		{"build.log", true},
+		{"Makefile", false},
+		{"cmd/local.go", false},
+	} {
+		if got := p.protects(c.name); got != c.want {
+			t.Errorf("protects(%q) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// offline disables network access. Fetching a source that is not cached fails.
+var offline bool
+
+// bundle is a document of presets, templates and a policy distributed over
+// HTTPS.
+type bundle struct {
+	Presets   map[string][]string `yaml:"presets,omitempty"`
+	Templates map[string]string   `yaml:"templates,omitempty"`
+	// Makefile defines sections replacing those of the same name of the
+	// built-in Makefile template.
+	Makefile string  `yaml:"makefile,omitempty"`
+	Policy   *policy `yaml:"policy,omitempty"`
+}
+
+// templateSet is what the bundles loaded for a project add to the built-in
+// presets and templates, so that the projects of a batch or a preview server
+// only see the bundles they were generated from.
+type templateSet struct {
+	presets   map[string][]string
+	templates map[string]string
+	// sections are the Makefile sections of each loaded bundle, in the
+	// order the bundles were loaded.
+	sections []bundleSections
+	policy   *policy
+}
+
+// bundleSections are the Makefile sections of the bundle at source.
+type bundleSections struct {
+	source, text string
+}
+
+// projectTemplates returns the templates of the project data is rendered for,
+// nil for the built-in ones.
+func projectTemplates(data map[string]interface{}) *templateSet {
+	set, _ := data["templateSet"].(*templateSet)
+	return set
+}
+
+// template returns the template called name, from the loaded bundles before
+// the built-in ones.
+func (s *templateSet) template(name string) (string, bool) {
+	if s != nil {
+		if text, ok := s.templates[name]; ok {
+			return text, true
+		}
+	}
+	text, ok := fileTemplates[name]
+	return text, ok
+}
+
+// preset returns the options of the preset called name, from the loaded
+// bundles before the built-in ones.
+func (s *templateSet) preset(name string) ([]string, bool) {
+	if s != nil {
+		if options, ok := s.presets[name]; ok {
+			return options, true
+		}
+	}
+	options, ok := presets[name]
+	return options, ok
+}
+
+// makefileSections returns the Makefile sections of the loaded bundles.
+func (s *templateSet) makefileSections() []string {
+	if s == nil {
+		return nil
+	}
+	texts := make([]string, len(s.sections))
+	for i, sections := range s.sections {
+		texts[i] = sections.text
+	}
+	return texts
+}
+
+// orgPolicy returns the policy of the loaded bundles, nil without one.
+func (s *templateSet) orgPolicy() *policy {
+	if s == nil {
+		return nil
+	}
+	return s.policy
+}
+
+// updateSources verifies the sources against the checksums they publish
+// rather than those recorded in the lock file of the project, accepting a
+// source that changed since it was pinned.
+var updateSources bool
+
+// loadPresetSource fetches the bundle at url, verifies it and adds its presets
+// and templates to set, replacing any of the same name, and enforces its
+// policy on the projects generated from set. Loading a bundle again replaces
+// what it added before. When keys are given the bundle must also be signed by
+// one of them. It returns the checksum of the bundle.
+func loadPresetSource(ctx context.Context, url string, trusted *lock, keys []trustedKey, set *templateSet) (string, error) {
+	contents, ok := cachedSource(url, trusted)
+	if !ok {
+		var err error
+		if contents, err = fetch(ctx, url); err != nil {
+			return "", err
+		}
+	}
+	sum, err := verify(ctx, url, contents, trusted)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) > 0 {
+		if err := verifySignature(ctx, url, contents, keys); err != nil {
+			return "", err
+		}
+	}
+
+	var b bundle
+	if err := yaml.Unmarshal(contents, &b); err != nil {
+		return "", fmt.Errorf("%s: %v", url, err)
+	}
+	for name, options := range b.Presets {
+		for _, option := range options {
+			if !isOption(option) {
+				return "", fmt.Errorf("%s: preset %s: unknown option %q", url, name, option)
+			}
+		}
+	}
+	if b.Policy != nil {
+		b.Policy.source = url
+		if err := b.Policy.check(); err != nil {
+			return "", err
+		}
+	}
+
+	if set.presets == nil {
+		set.presets, set.templates = map[string][]string{}, map[string]string{}
+	}
+	for name, options := range b.Presets {
+		set.presets[name] = options
+	}
+	for name, text := range b.Templates {
+		set.templates[name] = text
+	}
+	var sections []bundleSections
+	for _, s := range set.sections {
+		if s.source != url {
+			sections = append(sections, s)
+		}
+	}
+	if b.Makefile != "" {
+		sections = append(sections, bundleSections{url, b.Makefile})
+	}
+	set.sections = sections
+	if b.Policy != nil {
+		set.policy = b.Policy
+	}
+	return sum, nil
+}
+
+// verify checks contents against the checksum of url recorded in trusted, or
+// against the checksums file published at url + ".sha256" when none is
+// recorded, and returns the checksum of contents. That checksums file comes
+// from the same origin as the source, so it only catches a corrupted download:
+// a source without a lock entry is trusted on first use. Only the lock file
+// recording its checksum, or a signature by one of the trustedKeys of the user
+// config, protects the later runs against a changed source.
+func verify(ctx context.Context, url string, contents []byte, trusted *lock) (string, error) {
+	sum := sha256Sum(contents)
+
+	want, ok := trusted.checksum(url)
+	if !ok {
+		checksums, err := fetch(ctx, url+".sha256")
+		if err != nil {
+			return "", fmt.Errorf("%s: refusing unverified source, it has no %s entry and %v", url, lockFile, err)
+		}
+		want = checksumFor(string(checksums), path.Base(url))
+	}
+	if !strings.EqualFold(want, sum) && ok {
+		return "", fmt.Errorf("%s: checksum mismatch, got %s, want %s as recorded in %s, rerun with -update-sources to accept it", url, sum, want, lockFile)
+	}
+	if !strings.EqualFold(want, sum) {
+		return "", fmt.Errorf("%s: checksum mismatch, got %s, want %s", url, sum, want)
+	}
+	return sum, nil
+}
+
+// checksumFor returns the checksum of name in a sha256sum formatted file. A
+// file holding nothing but a single checksum applies to any name.
+func checksumFor(checksums, name string) string {
+	if fields := strings.Fields(checksums); len(fields) == 1 {
+		return fields[0]
+	}
+	for _, line := range strings.Split(checksums, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// fetch returns the body served at url. Responses are cached under the user
+// cache dir and revalidated with their ETag, and the cached copy is used when
+// offline or when the server cannot be reached, but not when ctx is done.
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("%s: only https sources are supported", url)
+	}
+
+	fetchedBodies.Lock()
+	body, ok := fetchedBodies.bodies[url]
+	fetchedBodies.Unlock()
+	if ok {
+		return body, nil
+	}
+
+	cache := cachePath(url)
+	cached, cacheErr := ioutil.ReadFile(cache)
+	etag, _ := ioutil.ReadFile(cache + ".etag")
+	if offline {
+		if cacheErr != nil {
+			return nil, fmt.Errorf("%s: not cached and -offline disables network access", url)
+		}
+		return cached, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cacheErr == nil && len(etag) > 0 {
+		req.Header.Set("If-None-Match", string(etag))
+	}
+
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if cacheErr == nil {
+			fmt.Fprintf(os.Stderr, "warning: %v, using cached %s\n", err, url)
+			return cached, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cacheErr == nil:
+		remember(url, cached)
+		return cached, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	remember(url, body)
+	if err := os.MkdirAll(filepath.Dir(cache), os.ModePerm); err == nil {
+		ioutil.WriteFile(cache, body, 0644)
+		ioutil.WriteFile(cache+".etag", []byte(resp.Header.Get("ETag")), 0644)
+	}
+	return body, nil
+}
+
+// sha256Sum returns the hex encoded SHA-256 checksum of contents.
+func sha256Sum(contents []byte) string {
+	hash := sha256.Sum256(contents)
+	return hex.EncodeToString(hash[:])
+}
+
+// cachePath returns where the response for url is cached.
+func cachePath(url string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "maker", "http", sha256Sum([]byte(url)))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChecksumFor(t *testing.T) {
+	for _, c := range []struct {
+		name, checksums, file, want string
+	}{
+		{"sha256sum", "aaa  presets.yaml\nbbb  templates.yaml\n", "templates.yaml", "bbb"},
+		{"binary mode", "aaa *presets.yaml\n", "presets.yaml", "aaa"},
+		{"missing", "aaa  presets.yaml\n", "templates.yaml", ""},
+		{"bare", "ccc\n", "templates.yaml", "ccc"},
+		{"bare among others", "ccc\naaa  presets.yaml\n", "templates.yaml", ""},
+		{"bare after missing", "aaa  presets.yaml\nccc\n", "templates.yaml", ""},
+		{"empty", "", "templates.yaml", ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := checksumFor(c.checksums, c.file); got != c.want {
+				t.Errorf("checksumFor(%q, %q) = %q, want %q", c.checksums, c.file, got, c.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	defer func(old bool) { offline = old }(offline)
+	offline = true
+
+	const url = "https://maker.invalid/bundle.yaml"
+	contents := []byte("presets: {}\n")
+	sum := fmt.Sprintf("%x", sha256.Sum256(contents))
+	for _, c := range []struct {
+		name    string
+		trusted *lock
+		err     string
+	}{
+		{"recorded", &lock{Sources: []lockSource{{url, sum}}}, ""},
+		{"recorded upper case", &lock{Sources: []lockSource{{url, strings.ToUpper(sum)}}}, ""},
+		{"mismatch", &lock{Sources: []lockSource{{url, fmt.Sprintf("%x", sha256.Sum256([]byte("other")))}}}, "checksum mismatch"},
+		{"other source", &lock{Sources: []lockSource{{url + "?v=2", sum}}}, "refusing unverified source"},
+		{"unrecorded", &lock{}, "refusing unverified source"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := verify(context.Background(), url, contents, c.trusted)
+			switch {
+			case c.err == "" && err != nil:
+				t.Fatal(err)
+			case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+				t.Fatalf("verify = %v, want an error containing %q", err, c.err)
+			case c.err == "" && got != sum:
+				t.Errorf("verify = %q, want %q", got, sum)
+			}
+		})
+	}
+}
+
+func TestLoadPresetSourceTwice(t *testing.T) {
+	const url = "https://maker.invalid/twice/bundle.yaml"
+	body := []byte("makefile: |\n  {{define \"run\"}}run: phony{{end}}\n")
+	remember(url, body)
+	remember(url+".sha256", []byte(sha256Sum(body)+"  bundle.yaml\n"))
+
+	set := &templateSet{}
+	data := map[string]interface{}{"templateSet": set}
+	var sums []string
+	for i := 0; i < 2; i++ {
+		if _, err := loadPresetSource(context.Background(), url, &lock{}, nil, set); err != nil {
+			t.Fatal(err)
+		}
+		sum, err := templatesChecksum(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sums = append(sums, sum)
+	}
+	if got := len(set.makefileSections()); got != 1 {
+		t.Errorf("makefileSections has %d sections, want 1", got)
+	}
+	if sums[0] != sums[1] {
+		t.Errorf("templatesChecksum = %q after loading again, want %q", sums[1], sums[0])
+	}
+}
+
+func TestUpdateSources(t *testing.T) {
+	defer func(old bool) { updateSources = old }(updateSources)
+
+	const url = "https://maker.invalid/update/bundle.yaml"
+	body := []byte("presets:\n  org: [test]\n")
+	remember(url, body)
+	remember(url+".sha256", []byte(sha256Sum(body)+"  bundle.yaml\n"))
+
+	dir := t.TempDir()
+	pinned := &lock{Sources: []lockSource{{url, sha256Sum([]byte("presets: {}\n"))}}}
+	contents, err := pinned.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, lockFile), contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := map[string]interface{}{"presetSource": url}
+	updateSources = false
+	if _, _, err := projectData(context.Background(), dir, "project", config, nil); err == nil || !strings.Contains(err.Error(), "-update-sources") {
+		t.Fatalf("projectData = %v, want an error suggesting -update-sources", err)
+	}
+	updateSources = true
+	_, generated, err := projectData(context.Background(), dir, "project", config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := generated.checksum(url); got != sha256Sum(body) {
+		t.Errorf("recorded checksum = %q, want %q", got, sha256Sum(body))
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// renderTemplate renders the template called name. A file of that name in the
+// templatesDir takes precedence over the built-in template or the one from the
+// presetSource, and name is read as a path when none exists.
+func renderTemplate(ctx context.Context, name string, data map[string]interface{}) ([]byte, error) {
+	text, ok, err := overrideTemplate(name, data)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		text, ok = projectTemplates(data).template(name)
+	}
+	if !ok && name == "Makefile" {
+		return renderMakefile(ctx, data)
+	}
+	if !ok {
+		contents, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		text = string(contents)
+	}
+
+	templ, err := parseTemplate(ctx, name, text)
+	if err != nil {
+		return nil, templateFailure(name, err, data)
+	}
+	out, err := execute(ctx, templ, data)
+	if err != nil {
+		return nil, templateFailure(name, err, data)
+	}
+	return []byte(out), nil
+}
+
+// overrideTemplate returns the template called name in the templatesDir, if
+// one is set and contains it. A directory of that name, such as the Makefile
+// directory of block overrides, is not a template.
+func overrideTemplate(name string, data map[string]interface{}) (string, bool, error) {
+	dir, _ := data["templatesDir"].(string)
+	if dir == "" {
+		return "", false, nil
+	}
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if info, err := os.Stat(path); os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return "", false, nil
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	return string(contents), true, nil
+}
+
+// renderMakefile renders every block of makefileBlocks in order, each one
+// either from its override or section by section, and joins the non-empty
+// sections with a single blank line. The sections of loaded bundles replace
+// the built-in ones of the same name.
+func renderMakefile(ctx context.Context, data map[string]interface{}) ([]byte, error) {
+	templ, err := parseTemplate(ctx, "makefile", makefileTemplate)
+	if err != nil {
+		return nil, templateFailure("Makefile", err, data)
+	}
+	for _, text := range projectTemplates(data).makefileSections() {
+		if templ, err = templ.Parse(text); err != nil {
+			return nil, templateFailure("Makefile", err, data)
+		}
+	}
+
+	var sections []string
+	for _, b := range makefileBlocks {
+		text, ok, err := blockOverride(b.name, data)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			partials, err := templ.Clone()
+			if err != nil {
+				return nil, err
+			}
+			override, err := partials.New("Makefile/" + b.name).Parse(text)
+			if err != nil {
+				return nil, templateFailure("Makefile/"+b.name, err, data)
+			}
+			out, err := execute(ctx, override, data)
+			if err != nil {
+				return nil, templateFailure("Makefile/"+b.name, err, data)
+			}
+			if section := normalize(out); section != "" {
+				sections = append(sections, section)
+			}
+			continue
+		}
+		for _, name := range b.sections {
+			out, err := execute(ctx, templ.Lookup(name), data)
+			if err != nil {
+				return nil, templateFailure("Makefile", err, data)
+			}
+			section := normalize(out)
+			if section != "" && contains(experimentalTargets(data), name) {
+				section = guardExperimental(section, experimentalTargets(data))
+			}
+			if section != "" {
+				sections = append(sections, section)
+			}
+		}
+	}
+
+	return []byte(strings.Join(sections, "\n\n") + "\n"), nil
+}
+
+// templatesChecksum returns the checksum of the templates in effect for data:
+// the built-in ones along with those of the loaded bundles, the Makefile
+// sections the bundles define and every template of the templatesDir, which
+// take precedence over them.
+func templatesChecksum(data map[string]interface{}) (string, error) {
+	set := projectTemplates(data)
+	effective := struct {
+		Templates map[string]string `yaml:"templates"`
+		Makefile  string            `yaml:"makefile"`
+		Sections  []string          `yaml:"sections,omitempty"`
+		Overrides map[string]string `yaml:"overrides,omitempty"`
+	}{map[string]string{}, makefileTemplate, set.makefileSections(), map[string]string{}}
+	for name, text := range fileTemplates {
+		effective.Templates[name] = text
+	}
+	if set != nil {
+		for name, text := range set.templates {
+			effective.Templates[name] = text
+		}
+	}
+
+	if dir, _ := data["templatesDir"].(string); dir != "" {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			contents, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			effective.Overrides[filepath.ToSlash(rel)] = string(contents)
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	out, err := yaml.Marshal(effective)
+	if err != nil {
+		return "", err
+	}
+	return sha256Sum(out), nil
+}
+
+// guardExperimental renders a section only with EXPERIMENTAL=1, labeling its
+// experimental targets in help. Without it each target is a rule explaining
+// how to run it, left out of help so that it is only listed once.
+func guardExperimental(section string, targets []string) string {
+	var guarded, fallback []string
+	for _, line := range strings.Split(section, "\n") {
+		if m := makeRule.FindStringSubmatch(line); m != nil && m[2] != "" && !strings.HasPrefix(line, "\t") && !makeAssignment.MatchString(line) {
+			target := strings.Fields(line[:strings.Index(line, ":")])[0]
+			if contains(targets, target) {
+				line = m[1] + " ## " + experimentalLabel + m[2]
+				fallback = append(fallback, fmt.Sprintf("%s: phony\n\t@echo \"%s is experimental, run it with EXPERIMENTAL=1\" >&2; exit 1", target, target))
+			}
+		}
+		guarded = append(guarded, line)
+	}
+	return "ifeq ($(EXPERIMENTAL),1)\n" + strings.Join(guarded, "\n") + "\nelse\n" + strings.Join(fallback, "\n\n") + "\nendif"
+}
+
+// blockOverride returns the template replacing the Makefile block called name
+// for the project type, preferring Makefile/TYPE/NAME over Makefile/NAME and
+// the templatesDir over the presetSource.
+func blockOverride(name string, data map[string]interface{}) (string, bool, error) {
+	for _, key := range []string{fmt.Sprintf("Makefile/%s/%s", data["type"], name), "Makefile/" + name} {
+		text, ok, err := overrideTemplate(key, data)
+		if err != nil || ok {
+			return text, ok, err
+		}
+		if text, ok := projectTemplates(data).template(key); ok {
+			return text, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// templateError is a template that failed to parse or render, with where it
+// failed and the data it was rendered with.
+type templateError struct {
+	name string
+	// line is the line of the template that failed, or 0 when unknown.
+	line int
+	// variable is the field or key of the data the template failed at.
+	variable string
+	message  string
+	keys     []string
+}
+
+func (e *templateError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.name)
+	if e.line > 0 {
+		fmt.Fprintf(&b, ":%d", e.line)
+	}
+	b.WriteString(": " + e.message)
+	if e.variable != "" {
+		b.WriteString("\n  variable: " + e.variable)
+	}
+	if len(e.keys) > 0 {
+		b.WriteString("\n  available keys: " + strings.Join(e.keys, ", "))
+	}
+	return b.String()
+}
+
+var (
+	// templateLocation matches the location text/template prefixes its
+	// errors with: the template name, line and, when executing, column.
+	templateLocation = regexp.MustCompile(`^template: ([^:]*):(\d+):(?:\d+:)? ?(.*)$`)
+	// templateVariable matches the action an execution error happened at,
+	// which names the variable when it is one.
+	templateVariable = regexp.MustCompile(`^executing "[^"]*" at <([^>]*)>: (.*)$`)
+)
+
+// templateFailure returns err, from parsing or rendering the template called
+// name, as a templateError listing the keys data provides.
+func templateFailure(name string, err error, data map[string]interface{}) error {
+	if errors.Is(err, errOutputTooLarge) {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	e := &templateError{name: name, message: err.Error()}
+	if m := templateLocation.FindStringSubmatch(e.message); m != nil {
+		// The sections of the Makefile are parsed as the makefile template.
+		if m[1] != "makefile" {
+			e.name = m[1]
+		}
+		e.line, _ = strconv.Atoi(m[2])
+		e.message = m[3]
+	}
+	if m := templateVariable.FindStringSubmatch(e.message); m != nil {
+		e.message = m[2]
+		if strings.HasPrefix(m[1], ".") || strings.HasPrefix(m[1], "$") {
+			e.variable = m[1]
+		} else {
+			e.message += " at " + m[1]
+		}
+	}
+	for key := range data {
+		e.keys = append(e.keys, key)
+	}
+	sort.Strings(e.keys)
+	return e
+}
+
+// normalize strips trailing whitespace from every line, drops leading and
+// trailing blank lines and collapses runs of blank lines into one.
+func normalize(s string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
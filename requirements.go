@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// requiresComment starts the comment above a documented rule of a generated
+// Makefile listing the commands its recipe runs, which make requirements and
+// maker doctor read.
+const requiresComment = "# requires:"
+
+// commandHints explain how to install the commands the targets need.
+var commandHints = map[string]string{
+	"go":        "install it from https://go.dev/dl/",
+	"git":       "install it from https://git-scm.com/downloads",
+	"awk":       "install awk, gawk or mawk with the package manager",
+	"docker":    "install it from https://docs.docker.com/get-docker/",
+	"python3":   "install it from https://www.python.org/downloads/",
+	"npm":       "install Node.js from https://nodejs.org/",
+	"sha256sum": "install GNU coreutils, with brew install coreutils on macOS",
+	"install":   "install GNU coreutils, with brew install coreutils on macOS",
+	"sed":       "install sed with the package manager",
+	"systemctl": "run the target on a Linux host with systemd",
+}
+
+// commandHint returns how to install command.
+func commandHint(command string) string {
+	if hint, ok := commandHints[command]; ok {
+		return hint
+	}
+	return "install it with the package manager"
+}
+
+// requirementHints returns the enabled commands with how to install each, for
+// the requirements target.
+func requirementHints(data map[string]interface{}) []map[string]string {
+	var hints []map[string]string
+	for _, command := range enabledCommands(data) {
+		hints = append(hints, map[string]string{"command": command, "hint": commandHint(command)})
+	}
+	return hints
+}
+
+// annotateRequirements adds a requires comment above each documented rule of
+// a Makefile whose recipe runs any of commands. The requirements target is
+// left out, since the hints it prints name commands it does not run.
+func annotateRequirements(contents []byte, commands []string) []byte {
+	recipes := makefileRecipes(contents)
+	lines := strings.Split(string(contents), "\n")
+	var out []string
+	for i, line := range lines {
+		m := makeRule.FindStringSubmatch(line)
+		if m != nil && m[2] != "" && !strings.HasPrefix(line, "\t") && !makeAssignment.MatchString(line) &&
+			(i == 0 || !strings.HasPrefix(lines[i-1], requiresComment)) {
+			var needed []string
+			for _, target := range strings.Fields(line[:strings.Index(line, ":")]) {
+				if target == "requirements" {
+					continue
+				}
+				for _, command := range commands {
+					if !contains(needed, command) && runsCommand(recipes[target], command) {
+						needed = append(needed, command)
+					}
+				}
+			}
+			if len(needed) > 0 {
+				out = append(out, requiresComment+" "+strings.Join(needed, " "))
+			}
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, "\n"))
+}
+
+// targetRequirements returns the targets of a Makefile needing each command,
+// as its requires comments list them.
+func targetRequirements(contents []byte) map[string][]string {
+	needs := map[string][]string{}
+	lines := strings.Split(string(contents), "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], requiresComment) || !makeRule.MatchString(lines[i+1]) {
+			continue
+		}
+		targets := strings.Fields(lines[i+1][:strings.Index(lines[i+1], ":")])
+		for _, command := range strings.Fields(strings.TrimPrefix(lines[i], requiresComment)) {
+			needs[command] = append(needs[command], targets...)
+		}
+	}
+	return needs
+}
+
+// checkRequirements returns a finding for each command the targets of the
+// Makefile in dir need, as annotated, other than those doctor checks on its
+// own.
+func checkRequirements(dir string) []finding {
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "Makefile"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return []finding{{false, err.Error()}}
+	}
+	needs := targetRequirements(contents)
+	var commands []string
+	for command := range needs {
+		if command != "go" && command != "git" && command != "docker" {
+			commands = append(commands, command)
+		}
+	}
+	sort.Strings(commands)
+
+	var findings []finding
+	for _, command := range commands {
+		if _, err := exec.LookPath(command); err != nil {
+			findings = append(findings, finding{false, command + " is not on PATH, needed by " + strings.Join(needs[command], " ") + ", " + commandHint(command)})
+		} else {
+			findings = append(findings, finding{true, command})
+		}
+	}
+	return findings
+}
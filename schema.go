@@ -0,0 +1,157 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed maker.schema.json
+var schemaJSON []byte
+
+// schema is the subset of JSON Schema used by maker.schema.json.
+type schema struct {
+	Type                 string             `json:"type"`
+	Description          string             `json:"description"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Required             []string           `json:"required"`
+	Enum                 []string           `json:"enum"`
+}
+
+// configSchema returns the embedded schema of the config file.
+func configSchema() *schema {
+	var s schema
+	if err := json.Unmarshal(schemaJSON, &s); err != nil {
+		panic(err)
+	}
+	return &s
+}
+
+// schemaError is a config value that does not satisfy the schema.
+type schemaError struct {
+	Line    int
+	Column  int
+	Path    string
+	Rule    string
+	Message string
+}
+
+func (e schemaError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%d:%d: %s (%s)", e.Line, e.Column, e.Message, e.Rule)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s (%s)", e.Line, e.Column, e.Path, e.Message, e.Rule)
+}
+
+// validate checks node against s and returns every violation found. path is
+// the dotted config key of node and rule the JSON pointer of s in the schema.
+func (s *schema) validate(node *yaml.Node, path, rule string) []schemaError {
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return s.validate(node.Content[0], path, rule)
+	}
+
+	fail := func(n *yaml.Node, path, rule, format string, args ...interface{}) []schemaError {
+		return []schemaError{{n.Line, n.Column, path, rule, fmt.Sprintf(format, args...)}}
+	}
+
+	if s.Type != "" && nodeType(node) != s.Type && !(s.Type == "number" && nodeType(node) == "integer") {
+		return fail(node, path, rule+"/type", "expected %s, got %s", s.Type, nodeType(node))
+	}
+	if len(s.Enum) > 0 && !contains(s.Enum, node.Value) {
+		return fail(node, path, rule+"/enum", "must be one of %s", strings.Join(s.Enum, ", "))
+	}
+
+	var errs []schemaError
+	switch node.Kind {
+	case yaml.MappingNode:
+		for _, key := range s.Required {
+			if !hasKey(node, key) {
+				errs = append(errs, fail(node, path, rule+"/required", "missing required key %s", key)...)
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := key.Value
+			if path != "" {
+				keyPath = path + "." + key.Value
+			}
+			property, ok := s.Properties[key.Value]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs = append(errs, fail(key, keyPath, rule+"/additionalProperties",
+						"unknown key, expected one of %s", strings.Join(s.keys(), ", "))...)
+				}
+				continue
+			}
+			errs = append(errs, property.validate(value, keyPath, rule+"/properties/"+key.Value)...)
+		}
+	case yaml.SequenceNode:
+		if s.Items != nil {
+			for i, item := range node.Content {
+				errs = append(errs, s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), rule+"/items")...)
+			}
+		}
+	}
+	return errs
+}
+
+// hasKey reports whether the mapping node has the key.
+func hasKey(node *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
+
+// keys returns the sorted property names of s.
+func (s *schema) keys() []string {
+	var keys []string
+	for key := range s.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// nodeType returns the JSON Schema type of a YAML node.
+func nodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	case yaml.AliasNode:
+		return nodeType(node.Alias)
+	}
+	switch node.ShortTag() {
+	case "!!bool":
+		return "boolean"
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
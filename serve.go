@@ -0,0 +1,378 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serve starts the preview server, a local web page to toggle the type and
+// features of a project, preview its rendered files and download or write it.
+// It only listens on the loopback address, since it writes to the disk.
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "The address to listen on, localhost or 127.0.0.1 with a port")
+	flags.Parse(args)
+
+	if len(flags.Args()) != 0 {
+		fmt.Println("Expected use: maker serve [-addr ADDR]")
+		os.Exit(1)
+	}
+	host, port, err := net.SplitHostPort(*addr)
+	if err == nil && host != "localhost" && host != "127.0.0.1" {
+		err = fmt.Errorf("%s: maker serve only listens on localhost", *addr)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", port))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	user, err := loadConfig(userConfigFile())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	project, err := loadConfig(projectConfigFile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	s := &previewServer{
+		defaults: mergeConfigs(envConfig(), user, project),
+		keys:     trustedKeys(user),
+		options:  options(),
+		port:     strconv.Itoa(listener.Addr().(*net.TCPAddr).Port),
+		token:    hex.EncodeToString(token),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.page)
+	mux.HandleFunc("/preview", s.preview)
+	mux.HandleFunc("/download", s.download)
+	mux.HandleFunc("/write", s.write)
+	mux.HandleFunc("/schema", s.schema)
+	fmt.Printf("Serving previews on http://localhost:%s\n", s.port)
+	if err := http.Serve(listener, s.local(mux)); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// previewServer renders the projects described by the query of a request on
+// top of the configs maker serve was started with.
+type previewServer struct {
+	defaults map[string]interface{}
+	keys     []trustedKey
+	options  []option
+	// port is the port listened on, which the Host of every request must
+	// name along with localhost or 127.0.0.1, so that a site rebinding its
+	// DNS name to the loopback address cannot reach the server.
+	port string
+	// token is the secret of this session, which the page sends to write.
+	token string
+	// mu renders one project at a time, since options such as -offline are
+	// package state.
+	mu sync.Mutex
+}
+
+// local serves only the requests addressed to localhost or 127.0.0.1 and the
+// port listened on.
+func (s *previewServer) local(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "localhost:"+s.port && r.Host != "127.0.0.1:"+s.port {
+			http.Error(w, r.Host+" is not the address of maker serve", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// previewFields are the string options a request may set besides the
+// features.
+var previewFields = []string{"type", "preset", "mod", "lang", "format", "description"}
+
+// projectName returns the project name of the query, defaulting to project.
+func projectName(query url.Values) string {
+	if name := query.Get("name"); name != "" {
+		return name
+	}
+	return "project"
+}
+
+// render renders the project described by the query into memory.
+func (s *previewServer) render(r *http.Request) (*memFS, error) {
+	query := r.URL.Query()
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		query = r.Form
+	}
+	config := map[string]interface{}{}
+	for _, key := range previewFields {
+		if v := query.Get(key); v != "" {
+			config[key] = v
+		}
+	}
+	for _, f := range features {
+		if query.Get(f.name) != "" {
+			config[f.name] = true
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, generated, err := projectData(r.Context(), "", projectName(query), mergeConfigs(s.defaults, config), s.keys)
+	if err != nil {
+		return nil, err
+	}
+	return renderProject(r.Context(), "", data, generated)
+}
+
+// page serves the form toggling the options along with the preview of the
+// files they render.
+func (s *previewServer) page(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var toggles []option
+	for _, o := range s.options {
+		if o.Feature {
+			toggles = append(toggles, o)
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	previewPage.Execute(w, map[string]interface{}{
+		"types":     projectTypes,
+		"languages": languages,
+		"formats":   taskFormats,
+		"presets":   names,
+		"features":  toggles,
+		"token":     s.token,
+	})
+}
+
+// schema answers the options as maker schema -json prints them.
+func (s *previewServer) schema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.options)
+}
+
+// previewFile is a rendered file as the page previews it.
+type previewFile struct {
+	Path     string `json:"path"`
+	Contents string `json:"contents"`
+}
+
+// preview answers the rendered files of the project as JSON, or the error
+// rendering it failed with.
+func (s *previewServer) preview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fsys, err := s.render(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	files := []previewFile{}
+	for _, p := range fsys.paths {
+		files = append(files, previewFile{p, string(fsys.files[p].contents)})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"files": files})
+}
+
+// download answers the project as a zip archive of the directory NAME.
+func (s *previewServer) download(w http.ResponseWriter, r *http.Request) {
+	fsys, err := s.render(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	name := projectName(r.URL.Query())
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".zip"))
+	archive := zip.NewWriter(w)
+	now := time.Now()
+	for _, p := range fsys.paths {
+		f := fsys.files[p]
+		header := &zip.FileHeader{Name: path.Join(name, p), Method: zip.Deflate, Modified: now}
+		header.SetMode(f.perm)
+		out, err := archive.CreateHeader(header)
+		if err != nil {
+			return
+		}
+		out.Write(f.contents)
+	}
+	archive.Close()
+}
+
+// write generates the project into the new directory dir of the form, which
+// must be within the directory maker serve runs in. Only requests from the
+// page itself, which sends the token of the session, may write, so that other
+// sites cannot.
+func (s *previewServer) write(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "write requires POST", http.StatusMethodNotAllowed)
+		return
+	}
+	origin := r.Header.Get("Origin")
+	token := r.Header.Get("X-Maker-Token")
+	if (origin != "" && origin != "http://"+r.Host) || s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		http.Error(w, "write is only allowed from the preview page", http.StatusForbidden)
+		return
+	}
+	fsys, err := s.render(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	dir := r.Form.Get("dir")
+	if dir == "" {
+		dir = projectName(r.Form)
+	}
+	dir = filepath.Clean(dir)
+	if filepath.IsAbs(dir) || dir == "." || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		http.Error(w, dir+" is not a directory within "+mustGetwd(), http.StatusBadRequest)
+		return
+	}
+	if err := fsys.commit(r.Context(), dir, false); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	for _, p := range fsys.paths {
+		report(created, filepath.Join(dir, filepath.FromSlash(p)))
+	}
+	fmt.Fprintf(w, "wrote %d files to %s\n", len(fsys.paths), dir)
+}
+
+// mustGetwd returns the working directory, or . when it is unknown.
+func mustGetwd() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return wd
+}
+
+// previewPage is the page of maker serve. It has no dependencies, rendering
+// the preview from /preview whenever an option changes.
+var previewPage = template.Must(template.New("serve").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>maker</title>
+<style>
+body { font-family: sans-serif; margin: 0; display: flex; height: 100vh; }
+form { width: 22em; padding: 1em; overflow-y: auto; border-right: 1px solid #ccc; }
+form label { display: block; margin: 0.2em 0; }
+main { flex: 1; padding: 1em; overflow-y: auto; }
+pre { background: #f6f8fa; padding: 0.5em; overflow-x: auto; }
+.error { color: #b00; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<form id="options">
+<label>Name <input name="name" value="project"></label>
+<label>Module <input name="mod" placeholder="github.com/me/project"></label>
+<label>Description <input name="description"></label>
+<label>Type <select name="type">{{range .types}}<option>{{.}}</option>{{end}}</select></label>
+<label>Lang <select name="lang">{{range .languages}}<option>{{.}}</option>{{end}}</select></label>
+<label>Format <select name="format"><option value="">make only</option>{{range .formats}}<option>{{.}}</option>{{end}}</select></label>
+<label>Preset <select name="preset"><option value="">none</option>{{range .presets}}<option>{{.}}</option>{{end}}</select></label>
+<fieldset>
+<legend>Features</legend>
+{{- range .features}}
+<label title="{{.Description}}"><input type="checkbox" name="{{.Name}}" value="1"> {{.Name}}</label>
+{{- end}}
+</fieldset>
+<p>
+<button type="button" id="download">Download</button>
+<input name="dir" placeholder="directory">
+<button type="button" id="write">Write</button>
+</p>
+<p id="message"></p>
+</form>
+<main id="files"></main>
+<script>
+var form = document.getElementById("options");
+var files = document.getElementById("files");
+var message = document.getElementById("message");
+var token = {{.token}};
+
+function query() {
+  return new URLSearchParams(new FormData(form)).toString();
+}
+
+function refresh() {
+  fetch("/preview?" + query()).then(function (resp) {
+    return resp.json();
+  }).then(function (body) {
+    files.textContent = "";
+    if (body.error) {
+      var p = document.createElement("p");
+      p.className = "error";
+      p.textContent = body.error;
+      files.appendChild(p);
+      return;
+    }
+    body.files.forEach(function (f) {
+      var details = document.createElement("details");
+      var summary = document.createElement("summary");
+      var pre = document.createElement("pre");
+      summary.textContent = f.path;
+      pre.textContent = f.contents;
+      details.appendChild(summary);
+      details.appendChild(pre);
+      files.appendChild(details);
+    });
+  });
+}
+
+form.addEventListener("input", refresh);
+document.getElementById("download").addEventListener("click", function () {
+  window.location = "/download?" + query();
+});
+document.getElementById("write").addEventListener("click", function () {
+  fetch("/write", {method: "POST", headers: {"X-Maker-Token": token}, body: new URLSearchParams(new FormData(form))}).then(function (resp) {
+    return resp.text();
+  }).then(function (text) {
+    message.textContent = text;
+  });
+});
+refresh();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestServeProjectName(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &previewServer{defaults: map[string]interface{}{}}
+	for _, name := range []string{"../../x", "a/b", `a\b`, ".."} {
+		t.Run(name, func(t *testing.T) {
+			for _, handler := range []http.HandlerFunc{s.preview, s.download} {
+				rec := httptest.NewRecorder()
+				handler(rec, httptest.NewRequest(http.MethodGet, "/preview?name="+name, nil))
+				if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "invalid project name") {
+					t.Errorf("%s answered %d %q, want %d with an invalid project name", name, rec.Code, rec.Body, http.StatusUnprocessableEntity)
+				}
+			}
+		})
+	}
+}
+
+func TestServeWrite(t *testing.T) {
+	defer func(old bool) { noExec = old }(noExec)
+	noExec = true
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &previewServer{defaults: map[string]interface{}{}, port: "8080", token: "secret"}
+	handler := s.local(http.HandlerFunc(s.write))
+	for _, c := range []struct {
+		name, host, origin, token string
+		code                      int
+	}{
+		{"rebound host", "evil.example:8080", "", "secret", http.StatusForbidden},
+		{"other port", "localhost:9090", "", "secret", http.StatusForbidden},
+		{"no token", "localhost:8080", "", "", http.StatusForbidden},
+		{"wrong token", "127.0.0.1:8080", "", "guess", http.StatusForbidden},
+		{"other origin", "localhost:8080", "http://evil.example", "secret", http.StatusForbidden},
+		{"page", "localhost:8080", "http://localhost:8080", "secret", http.StatusOK},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/write", strings.NewReader("name="+strings.ReplaceAll(c.name, " ", "-")))
+			req.Host = c.host
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if c.origin != "" {
+				req.Header.Set("Origin", c.origin)
+			}
+			if c.token != "" {
+				req.Header.Set("X-Maker-Token", c.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != c.code {
+				t.Errorf("write answered %d %q, want %d", rec.Code, rec.Body, c.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// servicesDir is where add-service scaffolds services in a monorepo.
+const servicesDir = "services"
+
+// servicesVariable matches the root Makefile variable listing the services its
+// fan-out targets iterate over.
+var servicesVariable = regexp.MustCompile(`^SERVICES\s*[:+?]?=`)
+
+// addService scaffolds a service under services/ of the monorepo in the
+// working directory, adds it to go.work and registers it in the root Makefile.
+func addService(args []string) {
+	flags := flag.NewFlagSet("add-service", flag.ExitOnError)
+	t := flags.String("type", "http", "Creates a service of this type (cli, http)")
+	flags.BoolVar(&noExec, "no-exec", false, "Prints the commands that would change the workspace instead of running them")
+	flags.Parse(args)
+
+	if len(flags.Args()) != 1 {
+		fmt.Println("Expected use: maker add-service [-type TYPE] [-no-exec] NAME")
+		os.Exit(1)
+	}
+	name := flags.Arg(0)
+	dir := path.Join(servicesDir, name)
+
+	ctx, stop := interruptContext()
+	defer stop()
+	if err := newService(ctx, dir, name, *t); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// newService generates the service name into dir and wires it into the
+// monorepo, stopping when ctx is done.
+func newService(ctx context.Context, dir, name, t string) error {
+	user, err := loadConfig(userConfigFile())
+	if err != nil {
+		return err
+	}
+	project, err := loadConfig(projectConfigFile)
+	if err != nil {
+		return err
+	}
+	config := mergeConfigs(envConfig(), user, project, map[string]interface{}{"type": t})
+
+	root, err := modulePath("go.mod")
+	if err != nil {
+		return err
+	}
+	if root != "" {
+		config["mod"] = root + "/" + dir
+	}
+	// The service builds with the go version of the root module.
+	if version, err := goDirective("go.mod"); err != nil {
+		return err
+	} else if version != "" {
+		config["minGoVersion"] = version
+	}
+
+	// A workspace pinning its tools at the root installs them once into its
+	// bin for every service.
+	if exists(toolsFile) {
+		config["workspace"] = strings.TrimSuffix(strings.Repeat("../", strings.Count(dir, "/")+1), "/")
+	}
+
+	if err := newProject(ctx, filepath.FromSlash(dir), name, config, trustedKeys(user)); err != nil {
+		return err
+	}
+	if err := useModule(ctx, dir); err != nil {
+		return err
+	}
+	registered, err := registerService("Makefile", name)
+	if err != nil {
+		return err
+	}
+	if !registered && !discoversModules("Makefile") {
+		fmt.Printf("No SERVICES variable in the root Makefile, add %s to its fan-out targets by hand\n", name)
+	}
+	return nil
+}
+
+// modulePath returns the module path declared in the go.mod file at path, or
+// an empty string when there is none.
+func modulePath(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(contents), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), nil
+		}
+	}
+	return "", nil
+}
+
+// moduleConfig sets the module path and go version of the go.mod file at path
+// on config, so that generating into an existing module keeps both rather than
+// needing -mod again. A module path or prefix the config sets is kept.
+func moduleConfig(path string, config map[string]interface{}) error {
+	module, err := modulePath(path)
+	if err != nil {
+		return err
+	}
+	if module != "" && config["mod"] == nil && config["modulePrefix"] == nil {
+		config["mod"] = module
+	}
+	version, err := goDirective(path)
+	if err != nil {
+		return err
+	}
+	if version != "" {
+		config["minGoVersion"] = version
+	}
+	return nil
+}
+
+// useModule adds dir to the go.work file of the working directory with go
+// work use, creating the file with go work init when needed.
+func useModule(ctx context.Context, dir string) error {
+	if _, err := os.Stat("go.work"); os.IsNotExist(err) {
+		args := []string{"work", "init"}
+		if _, err := os.Stat("go.mod"); err == nil {
+			args = append(args, ".")
+		}
+		if err := goCommand(ctx, args...); err != nil {
+			return err
+		}
+	}
+	return goCommand(ctx, "work", "use", "./"+dir)
+}
+
+// shareTools pins the tools of a module of a workspace in the tools file of
+// the workspace at path, appending those it does not pin yet. The versions it
+// pins already are kept, so that every module installs the same ones.
+func shareTools(path string, tools []map[string]string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var pinned map[string]string
+	if err := yaml.Unmarshal(contents, &pinned); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	text := string(contents)
+	for _, t := range tools {
+		if _, ok := pinned[t["name"]]; ok {
+			continue
+		}
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		text += t["name"] + ": " + t["version"] + "\n"
+		pinned[t["name"]] = t["version"]
+	}
+	if text == string(contents) {
+		return nil
+	}
+	if dryRun {
+		report(wouldUpdate, path)
+		return nil
+	}
+	if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		return err
+	}
+	report(updated, path)
+	return nil
+}
+
+// goCommand runs the go command with args.
+func goCommand(ctx context.Context, args ...string) error {
+	_, err := runCommand(ctx, "", "go", args...)
+	return err
+}
+
+// registerService appends name to the SERVICES variable of the Makefile at
+// path and reports whether the variable was found.
+func registerService(path, name string) (bool, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var lines []string
+	registered := false
+	scanner := bufio.NewScanner(strings.NewReader(string(contents)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !registered && servicesVariable.MatchString(line) {
+			line = strings.TrimRight(line, " ") + " " + name
+			registered = true
+		}
+		lines = append(lines, line)
+	}
+	if !registered && !discoversModules("Makefile") {
+		return false, nil
+	}
+	return true, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// discoversModules reports whether the Makefile at path finds its modules
+// itself, as the monorepo Makefile does.
+func discoversModules(path string) bool {
+	contents, err := ioutil.ReadFile(path)
+	return err == nil && strings.Contains(string(contents), "MODULES ?=")
+}
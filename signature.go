@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// trustedKey is a public key that remote sources may be signed with. Exactly
+// one of its fields is set.
+type trustedKey struct {
+	// Minisign is a minisign public key, the base64 line of a .pub file.
+	Minisign string
+	// Cosign is a PEM encoded ECDSA or Ed25519 public key, as written by
+	// cosign generate-key-pair.
+	Cosign string
+}
+
+// trustedKeys returns the trustedKeys of a merged config.
+func trustedKeys(config map[string]interface{}) []trustedKey {
+	var keys []trustedKey
+	values, _ := config["trustedKeys"].([]interface{})
+	for _, value := range values {
+		fields, _ := value.(map[string]interface{})
+		minisign, _ := fields["minisign"].(string)
+		cosign, _ := fields["cosign"].(string)
+		keys = append(keys, trustedKey{minisign, cosign})
+	}
+	return keys
+}
+
+// verifySignature checks that contents served at url carry a detached
+// signature by one of keys. Minisign signatures are read from url + ".minisig"
+// and cosign signatures from url + ".sig".
+func verifySignature(ctx context.Context, url string, contents []byte, keys []trustedKey) error {
+	var errs []string
+	for _, key := range keys {
+		var err error
+		switch {
+		case key.Minisign != "":
+			var sig []byte
+			if sig, err = fetch(ctx, url+".minisig"); err == nil {
+				err = verifyMinisign(key.Minisign, contents, sig)
+			}
+		case key.Cosign != "":
+			var sig []byte
+			if sig, err = fetch(ctx, url+".sig"); err == nil {
+				err = verifyCosign(key.Cosign, contents, sig)
+			}
+		default:
+			err = errors.New("trusted key has neither minisign nor cosign set")
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return fmt.Errorf("%s: no valid signature by a trusted key: %s", url, strings.Join(errs, "; "))
+}
+
+// verifyMinisign checks a minisign signature file sig of contents by the
+// public key pub.
+func verifyMinisign(pub string, contents, sig []byte) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(pub))
+	if err != nil || len(key) != 42 || string(key[:2]) != "Ed" {
+		return errors.New("invalid minisign public key")
+	}
+	keyID, publicKey := key[2:10], ed25519.PublicKey(key[10:])
+
+	lines := strings.Split(strings.TrimSpace(string(sig)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return errors.New("invalid minisign signature file")
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(signature) != 74 {
+		return errors.New("invalid minisign signature")
+	}
+	if !bytes.Equal(signature[2:10], keyID) {
+		return errors.New("minisign signature is by a different key")
+	}
+
+	message := contents
+	switch string(signature[:2]) {
+	case "Ed":
+	case "ED":
+		hash := blake2b.Sum512(contents)
+		message = hash[:]
+	default:
+		return errors.New("unsupported minisign signature algorithm")
+	}
+	if !ed25519.Verify(publicKey, message, signature[10:]) {
+		return errors.New("minisign signature does not match")
+	}
+
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil {
+		return errors.New("invalid minisign global signature")
+	}
+	comment := strings.TrimPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	signed := append(append([]byte{}, signature[10:]...), comment...)
+	if !ed25519.Verify(publicKey, signed, global) {
+		return errors.New("minisign trusted comment does not match")
+	}
+	return nil
+}
+
+// verifyCosign checks a base64 encoded cosign blob signature sig of contents
+// by the PEM encoded public key pub.
+func verifyCosign(pub string, contents, sig []byte) error {
+	block, _ := pem.Decode([]byte(pub))
+	if block == nil {
+		return errors.New("invalid cosign public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid cosign public key: %v", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return errors.New("invalid cosign signature")
+	}
+
+	hash := sha256.Sum256(contents)
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, hash[:], signature) {
+			return errors.New("cosign signature does not match")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, contents, signature) {
+			return errors.New("cosign signature does not match")
+		}
+	default:
+		return errors.New("unsupported cosign public key type")
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisignKey returns a minisign key pair with the key ID id, the public key
+// encoded as the base64 line of a .pub file.
+func minisignKey(id byte) (string, ed25519.PrivateKey) {
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{id}, ed25519.SeedSize))
+	keyID := bytes.Repeat([]byte{id}, 8)
+	pub := append(append([]byte("Ed"), keyID...), priv.Public().(ed25519.PublicKey)...)
+	return base64.StdEncoding.EncodeToString(pub), priv
+}
+
+// minisign returns the signature file minisign writes for contents, signed
+// with the algorithm Ed (legacy) or ED (prehashed).
+func minisign(priv ed25519.PrivateKey, id byte, algorithm string, contents []byte, comment string) []byte {
+	message := contents
+	if algorithm == "ED" {
+		hash := blake2b.Sum512(contents)
+		message = hash[:]
+	}
+	signature := ed25519.Sign(priv, message)
+	global := ed25519.Sign(priv, append(append([]byte{}, signature...), comment...))
+	sig := append(append([]byte(algorithm), bytes.Repeat([]byte{id}, 8)...), signature...)
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(sig) + "\n" +
+		"trusted comment: " + comment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	contents := []byte("presets: {}\n")
+	pub, priv := minisignKey(1)
+	other, otherPriv := minisignKey(2)
+	for _, c := range []struct {
+		name     string
+		pub      string
+		sig      []byte
+		contents []byte
+		ok       bool
+	}{
+		{"legacy", pub, minisign(priv, 1, "Ed", contents, "timestamp:1"), contents, true},
+		{"prehashed", pub, minisign(priv, 1, "ED", contents, "timestamp:1"), contents, true},
+		{"tampered contents", pub, minisign(priv, 1, "ED", contents, "timestamp:1"), []byte("presets: {evil: []}\n"), false},
+		{"other key", other, minisign(priv, 1, "ED", contents, "timestamp:1"), contents, false},
+		{"other key id", pub, minisign(otherPriv, 2, "ED", contents, "timestamp:1"), contents, false},
+		{"tampered comment", pub, bytes.Replace(minisign(priv, 1, "ED", contents, "timestamp:1"), []byte("timestamp:1"), []byte("timestamp:2"), 1), contents, false},
+		{"invalid key", "not a key", minisign(priv, 1, "ED", contents, "timestamp:1"), contents, false},
+		{"invalid file", pub, []byte("untrusted comment: x\n"), contents, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			err := verifyMinisign(c.pub, c.contents, c.sig)
+			if c.ok && err != nil {
+				t.Fatal(err)
+			}
+			if !c.ok && err == nil {
+				t.Fatal("verifyMinisign accepted the signature")
+			}
+		})
+	}
+}
+
+func TestVerifyCosign(t *testing.T) {
+	contents := []byte("presets: {}\n")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(contents)
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecKey, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSig := ed25519.Sign(edPriv, contents)
+
+	encode := func(key interface{}) string {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	}
+	sig := func(b []byte) []byte { return []byte(base64.StdEncoding.EncodeToString(b) + "\n") }
+	for _, c := range []struct {
+		name     string
+		pub      string
+		sig      []byte
+		contents []byte
+		ok       bool
+	}{
+		{"ecdsa", encode(&ecKey.PublicKey), sig(ecSig), contents, true},
+		{"ed25519", encode(edPub), sig(edSig), contents, true},
+		{"ecdsa tampered", encode(&ecKey.PublicKey), sig(ecSig), []byte("presets: {evil: []}\n"), false},
+		{"ed25519 tampered", encode(edPub), sig(edSig), []byte("presets: {evil: []}\n"), false},
+		{"wrong key", encode(edPub), sig(ecSig), contents, false},
+		{"invalid key", "not a key", sig(ecSig), contents, false},
+		{"invalid signature", encode(edPub), []byte("%%%"), contents, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			err := verifyCosign(c.pub, c.contents, c.sig)
+			if c.ok && err != nil {
+				t.Fatal(err)
+			}
+			if !c.ok && err == nil {
+				t.Fatal("verifyCosign accepted the signature")
+			}
+		})
+	}
+}
+
+func TestTrustedKeys(t *testing.T) {
+	keys := trustedKeys(map[string]interface{}{"trustedKeys": []interface{}{
+		map[string]interface{}{"minisign": "RWQ"},
+		map[string]interface{}{"cosign": "-----BEGIN PUBLIC KEY-----"},
+	}})
+	want := []trustedKey{{Minisign: "RWQ"}, {Cosign: "-----BEGIN PUBLIC KEY-----"}}
+	if len(keys) != len(want) || keys[0] != want[0] || keys[1] != want[1] {
+		t.Errorf("trustedKeys = %v, want %v", keys, want)
+	}
+	if keys := trustedKeys(map[string]interface{}{}); len(keys) != 0 {
+		t.Errorf("trustedKeys of an empty config = %v, want none", keys)
+	}
+}
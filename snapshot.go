@@ -0,0 +1,185 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// snapshotFile is the archive maker snapshot writes and maker restore reads by
+// default, relative to the project.
+const snapshotFile = ".maker.snapshot.zip"
+
+// snapshotCommand runs maker snapshot, archiving the generated files of a
+// project along with its lock file.
+func snapshotCommand(args []string) {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	out := flags.String("o", "", "Writes the snapshot to this file, "+snapshotFile+" in the project by default")
+	flags.BoolVar(&plain, "plain", false, "Prints the snapshot without colors or glyphs")
+	flags.Parse(args)
+
+	if len(flags.Args()) > 1 {
+		fmt.Println("Expected use: maker snapshot [-o FILE] [DIR]")
+		os.Exit(1)
+	}
+	dir := "."
+	if len(flags.Args()) == 1 {
+		dir = flags.Arg(0)
+	}
+	if *out == "" {
+		*out = filepath.Join(dir, snapshotFile)
+	}
+
+	n, err := snapshot(dir, *out)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	report(created, fmt.Sprintf("%s with %d files", *out, n))
+}
+
+// restoreCommand runs maker restore, putting back the files of a snapshot.
+func restoreCommand(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := flags.String("i", "", "Restores the snapshot of this file, "+snapshotFile+" in the project by default")
+	flags.BoolVar(&plain, "plain", false, "Prints the restored files without colors or glyphs")
+	flags.Parse(args)
+
+	if len(flags.Args()) > 1 {
+		fmt.Println("Expected use: maker restore [-i FILE] [DIR]")
+		os.Exit(1)
+	}
+	dir := "."
+	if len(flags.Args()) == 1 {
+		dir = flags.Arg(0)
+	}
+	if *in == "" {
+		*in = filepath.Join(dir, snapshotFile)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	if err := restore(ctx, dir, *in); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// snapshot archives the lock file of the project in dir and every generated
+// file it records into the zip file out, and returns how many files it
+// archived. Generated files removed since are left out.
+func snapshot(dir, out string) (int, error) {
+	generated, err := readLock(filepath.Join(dir, lockFile))
+	if err != nil {
+		return 0, err
+	}
+	if len(generated.Files) == 0 {
+		return 0, fmt.Errorf("%s: no generated files recorded", filepath.Join(dir, lockFile))
+	}
+
+	tmp := out + fmt.Sprintf(".maker-%d", os.Getpid())
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+	archive := zip.NewWriter(f)
+	paths := []string{lockFile}
+	for _, file := range generated.Files {
+		paths = append(paths, file.Path)
+	}
+	n := 0
+	for _, p := range paths {
+		name := filepath.Join(dir, filepath.FromSlash(p))
+		info, err := os.Stat(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			f.Close()
+			return 0, err
+		}
+		contents, err := ioutil.ReadFile(name)
+		if err != nil {
+			f.Close()
+			return 0, err
+		}
+		header := &zip.FileHeader{Name: p, Method: zip.Deflate, Modified: info.ModTime()}
+		header.SetMode(info.Mode())
+		w, err := archive.CreateHeader(header)
+		if err == nil {
+			_, err = w.Write(contents)
+		}
+		if err != nil {
+			f.Close()
+			return 0, err
+		}
+		n++
+	}
+	if err := archive.Close(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	return n, os.Rename(tmp, out)
+}
+
+// restore writes the files of the snapshot in into the project in dir, over
+// whatever they became since, and removes the generated files the lock file
+// records now that the snapshot has not, such as those a regeneration added.
+func restore(ctx context.Context, dir, in string) error {
+	archive, err := zip.OpenReader(in)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	fsys := newMemFS()
+	for _, f := range archive.File {
+		if clean := filepath.ToSlash(filepath.Clean(f.Name)); clean != f.Name || filepath.IsAbs(f.Name) || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("%s: %s is not a path within the project", in, f.Name)
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		contents, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+		fsys.WriteFile(f.Name, contents, f.Mode().Perm())
+	}
+	if _, ok := fsys.files[lockFile]; !ok {
+		return fmt.Errorf("%s: not a snapshot, it has no %s", in, lockFile)
+	}
+
+	current, err := readLock(filepath.Join(dir, lockFile))
+	if err != nil {
+		return err
+	}
+	if err := fsys.overwrite(ctx, dir); err != nil {
+		return err
+	}
+	for _, p := range fsys.paths {
+		report(updated, filepath.Join(dir, filepath.FromSlash(p)))
+	}
+	for _, f := range current.Files {
+		if _, ok := fsys.files[f.Path]; ok {
+			continue
+		}
+		name := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		report(removed, name)
+	}
+	return nil
+}
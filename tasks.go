@@ -0,0 +1,95 @@
+package main
+
+import "strings"
+
+// taskFormats are the formats of the task scripts generated beside the
+// Makefile for machines without make.
+var taskFormats = []string{"psake", "scripts"}
+
+// task is a Makefile target as a list of go commands, which run the same from
+// PowerShell and sh. $VERSION and the NAME_VERSION variables of the tools are
+// defined by the scripts.
+type task struct {
+	Name        string
+	Description string
+	Commands    []string
+}
+
+// tasks returns the tasks mirroring the portable targets of the Makefile.
+// clean is left to the scripts, since removing a directory differs between
+// the shells.
+func tasks(data map[string]interface{}) []task {
+	build := task{"build", "build the binary", []string{`go build -tags release -ldflags "-X main.Version=$VERSION" -o bin/ ./...`}}
+	if data["library"] == true {
+		build = task{"build", "build the library", []string{"go build ./..."}}
+	}
+	lint := task{"lint", "lint the codes", nil}
+	vet := task{"vet", "vet the codes", []string{"go vet ./..."}}
+	for _, t := range enabledTools(data) {
+		run := `go run "` + t["package"] + "@$" + t["variable"] + `" ./...`
+		switch t["name"] {
+		case "golint":
+			lint.Commands = append(lint.Commands, run)
+		case "shadow":
+			vet.Commands = append(vet.Commands, run)
+		}
+	}
+
+	all := []task{
+		{"generate", "run the code generators", []string{"go generate ./..."}},
+		build,
+	}
+	if data["library"] != true {
+		meta := data["meta"].(projectMeta)
+		run := "go run " + meta.Main(meta.BinaryNames()[0])
+		if len(meta.Binaries) == 0 {
+			run = "go run main.go"
+		}
+		all = append(all, task{"run", "run the binary", []string{run}})
+	}
+	all = append(all, task{"fmt", "format the codes", []string{"go fmt ./..."}}, lint, vet)
+	if data["test"] == true {
+		all = append(all, task{"test", "test the codes", []string{"go test -v ./..."}})
+	}
+	if data["bench"] == true {
+		all = append(all, task{"bench", "test with benchmarks", []string{"go test -v -bench=. -benchmem ./..."}})
+	}
+	if data["test"] == true && data["cover"] == true {
+		all = append(all, task{"test-cover", "test with coverage merged across packages", []string{
+			"go test -v -coverpkg=./... -coverprofile=bin/cover.out ./...",
+			"go tool cover -func=bin/cover.out",
+		}})
+	}
+	if data["testRace"] == true {
+		all = append(all, task{"test-race", "test and check for race conditions", []string{"go test -race ./..."}})
+	}
+	return all
+}
+
+// usedTools returns the go tools the tasks run, whose versions the scripts
+// read from the tools file.
+func usedTools(data map[string]interface{}) []map[string]string {
+	var used []map[string]string
+	for _, t := range enabledTools(data) {
+		for _, task := range tasks(data) {
+			if strings.Contains(strings.Join(task.Commands, "\n"), "$"+t["variable"]) {
+				used = append(used, t)
+				break
+			}
+		}
+	}
+	return used
+}
+
+// psFunction returns the PowerShell function name of a task, build-race for
+// example becoming Invoke-BuildRace.
+func psFunction(name string) string {
+	var b strings.Builder
+	b.WriteString("Invoke-")
+	for _, part := range strings.Split(name, "-") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateEnums are the values of the string options templates compare with
+// eq, an unknown one making the comparison always false.
+var templateEnums = map[string][]string{
+	"type":         projectTypes,
+	"lang":         languages,
+	"format":       append([]string{""}, taskFormats...),
+	"shell":        {"", "bash", "sh"},
+	"assertions":   assertionLibraries,
+	"vcsHost":      append([]string{""}, vcsHostNames()...),
+	"ciProvider":   append([]string{""}, ciProviderNames()...),
+	"makeFeatures": {"", "4.x"},
+	"naming":       append([]string{""}, namingConventions...),
+}
+
+// templateCommand runs maker template, whose commands lint a bundle of custom
+// templates and write the built-in ones as a bundle.
+func templateCommand(args []string) {
+	if len(args) > 0 && args[0] == "bundle" {
+		templateBundle(args[1:])
+		return
+	}
+	if len(args) == 0 || args[0] != "lint" {
+		fmt.Println("Expected use: maker template lint [-plain] BUNDLE")
+		fmt.Println("       maker template bundle [-o FILE]")
+		os.Exit(1)
+	}
+	flags := flag.NewFlagSet("template lint", flag.ExitOnError)
+	flags.BoolVar(&plain, "plain", false, "Prints the problems without colors or glyphs")
+	flags.Parse(args[1:])
+
+	if len(flags.Args()) != 1 {
+		fmt.Println("Expected use: maker template lint [-plain] BUNDLE")
+		os.Exit(1)
+	}
+	problems, err := lintBundle(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if problems > 0 {
+		os.Exit(1)
+	}
+}
+
+// lintBundle reports the problems of every template of a bundle, a directory
+// laid out like the templatesDir, and returns how many it found.
+func lintBundle(dir string) (int, error) {
+	var names []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(names)
+
+	keys := map[string]bool{}
+	for key := range templateData("project") {
+		keys[key] = true
+	}
+	total := 0
+	for _, name := range names {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return total, err
+		}
+		problems := lintTemplate(name, string(contents), keys)
+		for _, p := range problems {
+			report(failed, p)
+		}
+		if len(problems) == 0 {
+			report(passed, name)
+		}
+		total += len(problems)
+	}
+	return total, nil
+}
+
+// lintTemplate returns the problems of the template called name.
+func lintTemplate(name, text string, keys map[string]bool) []string {
+	// The message catalogs are YAML, not templates.
+	if strings.HasPrefix(name, "locales/") {
+		return lintIndentation(name, text)
+	}
+	templ, err := template.New(name).Funcs(templateFuncs(context.Background())).Parse(text)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	l := &templateLinter{keys: keys}
+	for _, t := range templ.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		l.tree = t.Tree
+		l.node(t.Tree.Root, true)
+	}
+	problems := l.problems
+	if isMakefileTemplate(name) {
+		problems = append(problems, lintMakefileTemplate(name, text)...)
+	}
+	return append(problems, lintIndentation(name, text)...)
+}
+
+// isMakefileTemplate reports whether the template called name renders a
+// Makefile or a block of one.
+func isMakefileTemplate(name string) bool {
+	return path.Base(name) == "Makefile" || strings.HasPrefix(name, "Makefile/")
+}
+
+// templateLinter walks the parse tree of a template for the variables the
+// template data has not and the conditionals no option set satisfies.
+type templateLinter struct {
+	keys     map[string]bool
+	tree     *parse.Tree
+	problems []string
+}
+
+func (l *templateLinter) report(n parse.Node, format string, args ...interface{}) {
+	location, _ := l.tree.ErrorContext(n)
+	l.problems = append(l.problems, location+": "+fmt.Sprintf(format, args...))
+}
+
+// node lints n, where top is set while dot is still the template data rather
+// than an element of range or the value of with.
+func (l *templateLinter) node(n parse.Node, top bool) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			l.node(child, top)
+		}
+	case *parse.ActionNode:
+		l.pipe(n.Pipe, top)
+	case *parse.TemplateNode:
+		l.pipe(n.Pipe, top)
+	case *parse.IfNode:
+		l.pipe(n.Pipe, top)
+		l.conditional(n)
+		l.node(n.List, top)
+		l.node(n.ElseList, top)
+	case *parse.RangeNode:
+		l.pipe(n.Pipe, top)
+		l.node(n.List, false)
+		l.node(n.ElseList, top)
+	case *parse.WithNode:
+		l.pipe(n.Pipe, top)
+		l.node(n.List, false)
+		l.node(n.ElseList, top)
+	}
+}
+
+func (l *templateLinter) pipe(p *parse.PipeNode, top bool) {
+	if p == nil {
+		return
+	}
+	for _, cmd := range p.Cmds {
+		for _, arg := range cmd.Args {
+			l.arg(arg, top)
+		}
+	}
+}
+
+func (l *templateLinter) arg(n parse.Node, top bool) {
+	switch n := n.(type) {
+	case *parse.FieldNode:
+		if top && !l.keys[n.Ident[0]] {
+			l.report(n, "undefined variable .%s", n.Ident[0])
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 && !l.keys[n.Ident[1]] {
+			l.report(n, "undefined variable $.%s", n.Ident[1])
+		}
+	case *parse.ChainNode:
+		l.arg(n.Node, top)
+	case *parse.PipeNode:
+		l.pipe(n, top)
+	}
+}
+
+// conditional reports an if whose condition no option set satisfies: an and
+// of conflicting features, or an eq of an option with a value it cannot have.
+func (l *templateLinter) conditional(n *parse.IfNode) {
+	if len(n.Pipe.Cmds) != 1 {
+		return
+	}
+	args := n.Pipe.Cmds[0].Args
+	if len(args) < 3 {
+		return
+	}
+	fn, ok := args[0].(*parse.IdentifierNode)
+	if !ok {
+		return
+	}
+	switch fn.Ident {
+	case "and":
+		var enabled []string
+		for _, arg := range args[1:] {
+			if f, ok := arg.(*parse.FieldNode); ok && len(f.Ident) == 1 && isOption(f.Ident[0]) {
+				enabled = append(enabled, f.Ident[0])
+			}
+		}
+		for _, name := range enabled {
+			f, _ := lookupFeature(name)
+			for _, other := range enabled {
+				if contains(f.conflicts, other) {
+					l.report(n, "unreachable: %s conflicts with %s, so both are never enabled", name, other)
+					return
+				}
+			}
+		}
+	case "eq":
+		f, ok := args[1].(*parse.FieldNode)
+		if !ok || len(f.Ident) != 1 {
+			return
+		}
+		values, ok := templateEnums[f.Ident[0]]
+		if !ok {
+			return
+		}
+		for _, arg := range args[2:] {
+			if s, ok := arg.(*parse.StringNode); ok && !contains(values, s.Text) {
+				l.report(n, "unreachable: .%s is never %q, expected one of %s", f.Ident[0], s.Text, strings.Join(values, ", "))
+			}
+		}
+	}
+}
+
+// lintMakefileTemplate reports the targets of a Makefile template that make
+// help would not list and the recipes indented with spaces, which make
+// rejects.
+func lintMakefileTemplate(name, text string) []string {
+	var problems []string
+	inRule := false
+	for i, line := range strings.Split(text, "\n") {
+		location := fmt.Sprintf("%s:%d", name, i+1)
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "{{"):
+			continue
+		case strings.HasPrefix(line, "\t"):
+			continue
+		case strings.HasPrefix(line, " ") && inRule:
+			problems = append(problems, location+": recipe line indented with spaces instead of a tab")
+			continue
+		}
+		inRule = false
+		if strings.HasPrefix(line, "#") || makeAssignment.MatchString(line) {
+			continue
+		}
+		m := makeRule.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		inRule = true
+		target := strings.Fields(m[1][:strings.Index(m[1], ":")])
+		if len(target) == 0 || m[2] != "" {
+			continue
+		}
+		// File, pattern and special targets are not commands to list.
+		if strings.ContainsAny(target[0], "$/%.{") || target[0] == "phony" {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s: target %s has no ## help comment, so make help does not list it", location, target[0]))
+	}
+	return problems
+}
+
+// lintIndentation reports the lines of YAML templates and catalogs indented
+// with tabs, which YAML forbids.
+func lintIndentation(name, text string) []string {
+	if ext := path.Ext(name); ext != ".yml" && ext != ".yaml" {
+		return nil
+	}
+	var problems []string
+	for i, line := range strings.Split(text, "\n") {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if strings.Contains(indent, "\t") {
+			problems = append(problems, fmt.Sprintf("%s:%d: indented with a tab, which YAML forbids", name, i+1))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,1690 @@
+package main
+
+// block is a part of the Makefile made of sections of makefileTemplate. A
+// block can be replaced as a whole by a template called Makefile/NAME, or
+// Makefile/TYPE/NAME for one project type only, from the templatesDir or the
+// presetSource.
+type block struct {
+	name     string
+	sections []string
+}
+
+// makefileBlocks are the blocks of the Makefile in the order they are
+// rendered. Sections that render empty for an option set are omitted.
+var makefileBlocks = []block{
+	{"base", []string{"goal", "shell", "make-features", "variables", "cache", "bin", "tools", "phony"}},
+	{"quality", []string{"fmt", "lint", "lint-docker", "vet", "deadcode", "spellcheck", "secrets-scan"}},
+	{"build", []string{"generate", "build", "run", "debug", "clean"}},
+	{"lang", []string{"node", "python"}},
+	{"test", []string{"test", "test-offline", "bench", "fuzz", "mutate", "test-cover", "test-cover-html", "test-race", "build-race", "test-cpu", "test-mem"}},
+	{"release", []string{"licenses", "deps-graph", "apidiff", "docker-build", "image-scan", "goreleaser", "systemd", "dist", "tag", "release-notes"}},
+	{"goals", []string{"all", "check-make", "makefile-test", "requirements", "help", "help-json"}},
+}
+
+// makefileTemplate defines one named template per section of makefileBlocks.
+// The sections are partials: a block override can include any of them with
+// {{template "NAME" .}}.
+const makefileTemplate = `
+{{define "goal"}}
+.DEFAULT_GOAL := help
+{{end}}
+
+{{define "shell"}}
+{{- if eq .shell "bash"}}
+# Run recipes with bash, failing on errors, unset variables and failed pipes.
+SHELL := bash
+.SHELLFLAGS := -eu -o pipefail -c
+{{- else if eq .shell "sh"}}
+# Run recipes with the POSIX shell, failing on errors.
+SHELL := /bin/sh
+.SHELLFLAGS := -ec
+{{- end}}
+{{end}}
+
+{{define "make-features"}}
+{{- if eq .makeFeatures "4.x"}}
+# Run each recipe in a single shell that stops at the first failing line, and
+# keep the output of parallel targets apart.
+.ONESHELL:
+{{- if not .shell}}
+.SHELLFLAGS := -ec
+{{- end}}
+MAKEFLAGS += --output-sync=target
+
+MAKE_MIN_VERSION := 4.0
+{{- end}}
+{{end}}
+
+{{define "variables"}}
+{{- if .workspace}}
+# BIN is shared by the modules of the workspace, which install each tool into
+# it once.
+BIN = $(abspath $(CURDIR)/{{.workspace}}/bin)
+{{- else}}
+BIN = $(CURDIR)/bin
+{{- end}}
+VERSION ?= $(shell git describe --tags --always --dirty --match=v* 2> /dev/null || echo v0)
+{{- if and .test (or .cover .coverHTML)}}
+# COVERPKG are the packages coverage is measured in by every test binary, so
+# code that the tests of other packages exercise counts as covered.
+COVERPKG ?= ./...
+{{- end}}
+{{- if and .docker (not .library)}}
+IMAGE ?= {{.meta.Image}}
+# COMMIT and CREATED label the image with the revision and time it was built
+# from.
+COMMIT ?= $(shell git rev-parse HEAD 2> /dev/null)
+CREATED ?= $(shell date -u +%Y-%m-%dT%H:%M:%SZ)
+{{- end}}
+{{end}}
+
+{{define "cache"}}
+{{- if .cache}}
+CACHE = $(CURDIR)/.cache
+export GOCACHE ?= $(CACHE)/go-build
+export GOMODCACHE ?= $(CACHE)/go-mod
+export GOLANGCI_LINT_CACHE ?= $(CACHE)/golangci-lint
+{{- end}}
+{{end}}
+
+{{define "bin"}}
+$(BIN):
+	@mkdir -p $@
+{{end}}
+
+{{define "tools"}}
+{{- if .workspace}}
+# TOOLS pins the tools of every module of the workspace.
+TOOLS = $(abspath $(CURDIR)/{{.workspace}}/tools.yaml)
+{{- else}}
+TOOLS = $(CURDIR)/tools.yaml
+{{- end}}
+
+# tool-version returns the version of the tool $(1) pinned in TOOLS.
+tool-version = $(shell awk -F ': *' '$$1 == "$(1)" { print $$2 }' $(TOOLS))
+{{range tools .}}
+{{.variable}} ?= $(call tool-version,{{.name}})
+{{- end}}
+
+# go-install installs the package $(2) at version $(3) into BIN as the tool $(1),
+# recording the version in a sentinel file so that bumping it reinstalls.
+define go-install
+	@GOBIN=$(BIN) go install $(2)@$(3)
+	@rm -f $(BIN)/.$(1)-* && touch $(BIN)/.$(1)-$(3)
+endef
+
+{{- range tools .}}
+
+$(BIN)/.{{.name}}-$({{.variable}}): | $(BIN)
+{{- if .image}}
+	@docker pull {{.image}}:$({{.variable}})
+	@rm -f $(BIN)/.{{.name}}-* && touch $@
+{{- else}}
+	$(call go-install,{{.name}},{{.package}},$({{.variable}}))
+{{- end}}
+{{- end}}
+
+bootstrap: phony{{range tools .}} $(BIN)/.{{.name}}-$({{.variable}}){{end}} ## install the tools pinned in tools.yaml
+{{end}}
+
+{{define "phony"}}
+.PHONY:phony
+{{end}}
+
+{{define "fmt"}}
+fmt: phony ## format the codes
+	@go fmt ./...
+{{end}}
+
+{{define "lint"}}
+lint: phony $(BIN)/.golint-$(GOLINT_VERSION) ## lint the codes
+	@$(BIN)/golint ./...
+{{end}}
+
+{{define "lint-docker"}}
+{{- if .lintDocker}}
+lint-docker: phony ## lint the codes with golangci-lint in a container
+	@docker run --rm \
+		-v $(CURDIR):/app \
+		-w /app \
+{{- if .cache}}
+		-v $(GOCACHE):/cache/go-build -e GOCACHE=/cache/go-build \
+		-v $(GOMODCACHE):/cache/go-mod -e GOMODCACHE=/cache/go-mod \
+		-v $(GOLANGCI_LINT_CACHE):/cache/golangci-lint -e GOLANGCI_LINT_CACHE=/cache/golangci-lint \
+{{- end}}
+		golangci/golangci-lint:$(GOLANGCI_LINT_VERSION) \
+		golangci-lint run ./...
+{{- end}}
+{{end}}
+
+{{define "vet"}}
+vet: phony{{if .shadow}} $(BIN)/.shadow-$(SHADOW_VERSION){{end}} ## vet the codes
+	@go vet ./...
+{{- if .shadow}}
+	@$(BIN)/shadow ./...
+{{- end}}
+{{end}}
+
+{{define "deadcode"}}
+{{- if .deadcode}}
+# deadcode exits zero when it finds functions unreachable from the main packages
+# and tests, so its output is checked instead. staticcheck's U1000 check reports
+# unused symbols.
+deadcode: phony $(BIN)/.deadcode-$(DEADCODE_VERSION) $(BIN)/.staticcheck-$(STATICCHECK_VERSION) ## find dead code and unused symbols
+	@out=$$($(BIN)/deadcode -test ./...) && [ -z "$$out" ] || { echo "$$out"; exit 1; }
+	@$(BIN)/staticcheck -checks U1000 ./...
+{{- end}}
+{{end}}
+
+{{define "spellcheck"}}
+{{- if .spellcheck}}
+# SPELLCHECK_IGNORE are the words listed in spellcheck.ignore, which misspell
+# accepts as spelled.
+SPELLCHECK_IGNORE ?= $(shell grep -v -e '^#' -e '^$$' $(CURDIR)/spellcheck.ignore | paste -sd, -)
+
+spellcheck: phony $(BIN)/.misspell-$(MISSPELL_VERSION) ## check the spelling of the codes and docs
+	@find . \( -path ./bin -o -path ./.cache -o -path ./.git \) -prune -o \( -name '*.go' -o -name '*.md' \) -print | \
+		xargs $(BIN)/misspell -error $(if $(SPELLCHECK_IGNORE),-i $(SPELLCHECK_IGNORE))
+{{- end}}
+{{end}}
+
+{{define "secrets-scan"}}
+{{- if .secretsScan}}
+# GITLEAKS_FLAGS scan the working tree when the project is not a git repository
+# yet, and its history otherwise.
+GITLEAKS_FLAGS = --source . --config .gitleaks.toml --redact $(if $(wildcard .git),,--no-git)
+
+secrets-scan: phony $(BIN)/.gitleaks-$(GITLEAKS_VERSION) ## scan for secrets other than the accepted findings of the baseline
+	@$(BIN)/gitleaks detect $(GITLEAKS_FLAGS) --baseline-path .gitleaks-baseline.json
+
+# secrets-baseline accepts every current finding, so review them before
+# committing the baseline.
+secrets-baseline: phony $(BIN)/.gitleaks-$(GITLEAKS_VERSION) ## accept the current findings of secrets-scan into the baseline
+	@$(BIN)/gitleaks detect $(GITLEAKS_FLAGS) --exit-code 0 --report-format json --report-path .gitleaks-baseline.json
+{{- end}}
+{{- if .secretsHook}}
+
+install-hooks: phony ## run the git hooks of .githooks, which scan for secrets before pushing
+	@git config core.hooksPath .githooks
+{{- end}}
+{{end}}
+
+{{define "generate"}}
+generate: phony ## run the code generators
+	@go generate ./...
+{{end}}
+
+{{define "build"}}
+{{- if not .library}}
+build: phony | $(BIN) ## build the binary
+	@go build \
+		-tags release \
+		-ldflags '-X main.Version=$(VERSION)' \
+		-o $(BIN)/ ./...
+{{- else}}
+build: phony ## build the library
+	@go build ./...
+{{- end}}
+{{end}}
+
+{{define "run"}}
+{{- if and (not .library) .meta.Binaries}}
+# BINARIES are built from cmd/, and run runs BINARY.
+BINARIES = {{join .meta.Binaries " "}}
+BINARY ?= {{index .meta.Binaries 0}}
+
+run: phony ## run the binary BINARY
+	@go run ./cmd/$(BINARY)
+{{- else if not .library}}
+run: phony ## run the binary
+	@go run main.go
+{{- end}}
+{{end}}
+
+{{define "debug"}}
+{{- if .debug}}
+# dlv listens on DEBUG_HOST:DEBUG_PORT for a debugger to attach to. Set
+# DEBUG_HOST=0.0.0.0 to attach from another machine. debug passes ARGS to the
+# binary.
+DEBUG_HOST ?= 127.0.0.1
+DEBUG_PORT ?= 2345
+DLV_FLAGS = --headless --listen=$(DEBUG_HOST):$(DEBUG_PORT) --api-version=2 --accept-multiclient
+# DEBUG_PKG is the package debug-test runs the tests matching DEBUG_RUN of.
+DEBUG_PKG ?= .
+DEBUG_RUN ?= .
+
+debug: phony $(BIN)/.dlv-$(DLV_VERSION) | $(BIN) ## build the binary{{if .meta.Binaries}} BINARY{{end}} without optimizations and run it in dlv
+	@go build -gcflags='all=-N -l' -ldflags '-X main.Version=$(VERSION)' -o $(BIN)/debug/{{if .meta.Binaries}}$(BINARY) ./cmd/$(BINARY){{else}}{{.name}} .{{end}}
+	@$(BIN)/dlv exec $(DLV_FLAGS) $(BIN)/debug/{{if .meta.Binaries}}$(BINARY){{else}}{{.name}}{{end}} -- $(ARGS)
+
+debug-test: phony $(BIN)/.dlv-$(DLV_VERSION) ## run the tests of DEBUG_PKG matching DEBUG_RUN in dlv
+	@$(BIN)/dlv test $(DLV_FLAGS) $(DEBUG_PKG) -- -test.run '$(DEBUG_RUN)'
+{{- end}}
+{{end}}
+
+{{define "clean"}}
+clean: phony
+	rm -rf $(BIN)
+{{- if eq .lang "go+node"}} $(WEB)/node_modules $(WEB)/dist{{end}}
+{{- if eq .lang "go+python"}} $(VENV){{end}}
+{{end}}
+
+{{define "node"}}
+{{- if eq .lang "go+node"}}
+# WEB is the Node frontend, installed with npm ci once it has a lock file.
+# npm runs in it with --prefix, so that no recipe changes directory, which
+# would carry over to the next line with .ONESHELL.
+WEB ?= web
+NPM ?= npm
+
+$(WEB)/node_modules: $(WEB)/package.json
+	@if [ -f $(WEB)/package-lock.json ]; then $(NPM) --prefix $(WEB) ci; else $(NPM) --prefix $(WEB) install; fi
+	@touch $@
+
+npm-install: phony $(WEB)/node_modules ## install the dependencies of the frontend
+
+npm-build: phony $(WEB)/node_modules ## build the frontend
+	@$(NPM) --prefix $(WEB) run build
+
+npm-test: phony $(WEB)/node_modules ## test the frontend
+	@$(NPM) --prefix $(WEB) test
+{{- end}}
+{{end}}
+
+{{define "python"}}
+{{- if eq .lang "go+python"}}
+# PY_SRC are the Python scripts, linted with the ruff pinned in
+# requirements-dev.txt from a virtualenv in VENV, which ruff skips.
+PY_SRC ?= .
+PYTHON ?= python3
+VENV ?= .venv
+
+$(VENV)/bin/ruff: requirements-dev.txt
+	@$(PYTHON) -m venv $(VENV)
+	@$(VENV)/bin/pip install --quiet -r requirements-dev.txt
+	@touch $@
+
+venv: phony $(VENV)/bin/ruff ## create the virtualenv of the scripts
+
+py-lint: phony $(VENV)/bin/ruff ## lint the scripts
+	@$(VENV)/bin/ruff check $(PY_SRC)
+
+py-fmt: phony $(VENV)/bin/ruff ## format the scripts
+	@$(VENV)/bin/ruff format $(PY_SRC)
+{{- end}}
+{{end}}
+
+{{define "test"}}
+{{- if .test}}
+test: phony ## test the codes
+	@go test -v ./...
+{{- end}}
+{{end}}
+
+{{define "test-offline"}}
+{{- if .test}}
+# Tests that need the network or containers carry a //go:build !offline
+# constraint, so test-offline runs the rest of the suite without them.
+test-offline: phony ## test without network or container access
+	@go test -v -tags offline ./...
+{{- end}}
+{{end}}
+
+{{define "bench"}}
+{{- if .bench}}
+bench: phony ## test with benchmarks
+	@go test -v -bench=. -benchmem ./...
+{{- end}}
+{{end}}
+
+{{define "fuzz"}}
+{{- if .fuzz}}
+FUZZ ?=
+FUZZ_PKG ?= .
+FUZZTIME ?= 30s
+FUZZMINIMIZETIME ?= 60s
+
+fuzz: phony ## fuzz the fuzz test FUZZ in FUZZ_PKG for FUZZTIME
+	@test -n "$(FUZZ)" || { echo "Set FUZZ to the fuzz test to run, as in make fuzz FUZZ=FuzzParse"; exit 1; }
+	@go test -run='^$$' -fuzz='^$(FUZZ)$$' -fuzztime=$(FUZZTIME) -fuzzminimizetime=$(FUZZMINIMIZETIME) $(FUZZ_PKG)
+
+# Fuzzing keeps the inputs it generates in the build cache and writes minimized
+# failing inputs to testdata/fuzz, where go test replays them as seeds.
+fuzz-corpus: phony ## copy the cached corpus of FUZZ, or of every fuzz test, into testdata/fuzz
+	@pkg=$$(go list $(FUZZ_PKG)); dir=$$(go list -f '{{"{{.Dir}}"}}' $(FUZZ_PKG)); \
+	for corpus in "$$(go env GOCACHE)/fuzz/$$pkg"/$(or $(FUZZ),*); do \
+		[ -d "$$corpus" ] || continue; \
+		mkdir -p "$$dir/testdata/fuzz/$${corpus##*/}"; \
+		cp -n "$$corpus"/* "$$dir/testdata/fuzz/$${corpus##*/}/"; \
+	done
+
+fuzz-crashers: phony ## list the failing inputs fuzzing saved that are not committed yet
+	@git ls-files --others --exclude-standard -- '*testdata/fuzz/$(or $(FUZZ),*)/*'
+
+fuzz-clean: phony ## remove the cached corpus of every fuzz test
+	@go clean -fuzzcache
+{{- end}}
+{{end}}
+
+{{define "mutate"}}
+{{- if .mutation}}
+# mutate fails when the tests catch fewer than MUTATION_THRESHOLD percent of the
+# mutants gremlins generates.
+MUTATION_THRESHOLD ?= 60
+
+mutate: phony $(BIN)/.gremlins-$(GREMLINS_VERSION) ## test the tests by mutating the codes
+	@$(BIN)/gremlins unleash --threshold-efficacy $(MUTATION_THRESHOLD)
+{{- end}}
+{{end}}
+
+{{define "licenses"}}
+{{- if .licenseCheck}}
+# ALLOWED_LICENSES are the SPDX identifiers listed in licenses.allow. The
+# project's own packages are not checked.
+ALLOWED_LICENSES ?= $(shell grep -v -e '^#' -e '^$$' $(CURDIR)/licenses.allow | paste -sd, -)
+
+licenses: phony $(BIN)/.go-licenses-$(GO_LICENSES_VERSION) ## check the dependencies only use allowed licenses
+	@$(BIN)/go-licenses check --allowed_licenses=$(ALLOWED_LICENSES) --ignore $$(go list -m) ./...
+
+licenses-report: phony $(BIN)/.go-licenses-$(GO_LICENSES_VERSION) ## report the license of each dependency
+	@$(BIN)/go-licenses report --ignore $$(go list -m) ./...
+{{- end}}
+{{end}}
+
+{{define "deps-graph"}}
+{{- if .depsGraph}}
+# deps-graph keeps the edges of the module and of its direct dependencies, so
+# the diagram stays readable, and highlights the direct dependencies. go mod
+# graph lists modules only, so the standard library never shows up, and the go
+# and toolchain requirements are left out.
+deps-graph: phony ## draw the module dependency graph into docs/deps.md
+	@mkdir -p docs
+	@go mod graph | awk -v main="$$(go list -m)" ' \
+		function node(m) { if (!(m in id)) { id[m] = "n" length(id); print "  " id[m] "[\"" m "\"]" } return id[m] } \
+		BEGIN { fence = "\140\140\140"; print fence "mermaid"; print "graph LR" } \
+		$$2 ~ /^(go|toolchain)@/ { next } \
+		$$1 == main { direct[$$2] = 1 } \
+		$$1 == main || $$1 in direct { edges[++n] = node($$1) " --> " node($$2) } \
+		END { for (i = 1; i <= n; i++) print "  " edges[i]; \
+			print "  classDef direct stroke-width:3px"; \
+			for (m in direct) print "  class " id[m] " direct"; print fence }' > docs/deps.md
+{{- end}}
+{{end}}
+
+{{define "apidiff"}}
+{{- if .library}}
+# apidiff compares the exported API against the latest release tag and fails on
+# incompatible changes, so run it before tagging a release.
+apidiff: phony $(BIN)/.gorelease-$(GORELEASE_VERSION) ## check the API is compatible with the latest release
+	@base=$$(git describe --tags --abbrev=0 --match='v*' 2> /dev/null) || base=none; \
+	$(BIN)/gorelease -base=$$base
+{{- end}}
+{{end}}
+
+{{define "test-cover"}}
+{{- if and .test .cover}}
+test-cover: phony | $(BIN) ## test with coverage merged across packages
+	@go test -v -coverpkg=$(COVERPKG) -coverprofile=$(BIN)/cover.out ./...
+	@go tool cover -func=$(BIN)/cover.out
+{{- end}}
+{{end}}
+
+{{define "test-cover-html"}}
+{{- if and .test .coverHTML}}
+test-cover-html: phony ## test with coverage in an HTML view
+	@go test -v -coverpkg=$(COVERPKG) -coverprofile=c.out ./...
+	@go tool cover -html=c.out
+
+# cover-serve serves the report over HTTP instead of opening a browser, for
+# remote machines and dev containers that forward COVER_PORT.
+COVER_PORT ?= 8000
+
+cover-serve: phony | $(BIN) ## serve the HTML coverage report on COVER_PORT
+	@mkdir -p $(BIN)/cover
+	@go test -coverpkg=$(COVERPKG) -coverprofile=$(BIN)/cover/c.out ./...
+	@go tool cover -html=$(BIN)/cover/c.out -o $(BIN)/cover/index.html
+	@echo "Serving the coverage report on http://localhost:$(COVER_PORT)"
+	@python3 -m http.server $(COVER_PORT) --directory $(BIN)/cover
+{{- end}}
+{{end}}
+
+{{define "test-race"}}
+{{- if .testRace}}
+test-race: phony ## test and check for race conditions
+	@go test -race ./...
+{{- end}}
+{{end}}
+
+{{define "build-race"}}
+{{- if .race}}
+build-race: phony | $(BIN) ## build and check for race conditions
+	@go build -race -o $(BIN)/race/ ./...
+{{- end}}
+{{end}}
+
+{{define "test-cpu"}}
+{{- if .cpuProfile}}
+test-cpu: phony | $(BIN) ## test and profile CPU
+	@go test {{if .bench}}-bench=. -benchmem {{end}}-o $(BIN)/cpu.test -cpuprofile cpu.out ./...
+	@go tool pprof cpu.out
+{{- end}}
+{{end}}
+
+{{define "test-mem"}}
+{{- if .memProfile}}
+test-mem: phony | $(BIN) ## test and profile memory
+	@go test {{if .bench}}-bench=. -benchmem {{end}}-o $(BIN)/mem.test -memprofile mem.out ./...
+	@go tool pprof mem.out
+{{- end}}
+{{end}}
+
+{{define "docker-build"}}
+{{- if and .docker (not .library)}}
+{{- if .meta.Binaries}}
+docker-build: phony ## build the docker image of each binary, tagged IMAGE-BINARY
+	@for binary in $(BINARIES); do \
+		{{if .buildkitCache}}DOCKER_BUILDKIT=1 {{end}}docker build \
+			--build-arg VERSION=$(VERSION) \
+			--build-arg REVISION=$(COMMIT) \
+			--build-arg CREATED=$(CREATED) \
+			--build-arg BINARY=$$binary \
+			-t $(IMAGE){{.meta.Separator}}{{if eq .naming "camel"}}$$(echo $$binary | tr '[:upper:]' '[:lower:]'){{else}}$$binary{{end}}:$(VERSION) . || exit 1; \
+	done
+{{- else}}
+docker-build: phony ## build the docker image
+	@{{if .buildkitCache}}DOCKER_BUILDKIT=1 {{end}}docker build \
+		--build-arg VERSION=$(VERSION) \
+		--build-arg REVISION=$(COMMIT) \
+		--build-arg CREATED=$(CREATED) \
+		-t $(IMAGE):$(VERSION) .
+{{- end}}
+{{- end}}
+{{end}}
+
+{{define "image-scan"}}
+{{- if and .imageScan (not .library)}}
+# image-scan fails on the vulnerabilities of IMAGE_SCAN_SEVERITY, but for those
+# accepted in .trivyignore.
+IMAGE_SCAN_SEVERITY ?= HIGH,CRITICAL
+
+image-scan: phony docker-build $(BIN)/.trivy-$(TRIVY_VERSION) ## scan the docker image{{if .meta.Binaries}}s{{end}} for vulnerabilities of IMAGE_SCAN_SEVERITY
+	@for image in {{if .meta.Binaries}}$(foreach binary,$(BINARIES),$(IMAGE){{.meta.Separator}}{{if eq .naming "camel"}}$(shell echo $(binary) | tr '[:upper:]' '[:lower:]'){{else}}$(binary){{end}}:$(VERSION)){{else}}$(IMAGE):$(VERSION){{end}}; do \
+		docker run --rm \
+			-v /var/run/docker.sock:/var/run/docker.sock \
+			-v $(BIN)/trivy:/root/.cache/trivy \
+			-v $(CURDIR)/.trivyignore:/.trivyignore:ro \
+			aquasec/trivy:$(TRIVY_VERSION) image \
+			--exit-code 1 \
+			--severity $(IMAGE_SCAN_SEVERITY) \
+			--ignorefile /.trivyignore \
+			$$image || exit 1; \
+	done
+{{- end}}
+{{end}}
+
+{{define "systemd"}}
+{{- if .systemd}}
+# install-service installs the binary into PREFIX and renders the unit of
+# deploy/ to run it as SERVICE_USER. DESTDIR stages both for packaging.
+PREFIX ?= /usr/local
+SERVICE_USER ?= {{.name}}
+UNIT_DIR ?= /etc/systemd/system
+UNIT = $(DESTDIR)$(UNIT_DIR)/{{.name}}.service
+
+install-service: phony | $(BIN) ## install the binary and its systemd unit, then start it
+	@go build -tags release -ldflags '-X main.Version=$(VERSION)' -o $(BIN)/service/{{.name}} .
+	@install -D -m 0755 $(BIN)/service/{{.name}} $(DESTDIR)$(PREFIX)/bin/{{.name}}
+	@mkdir -p $(dir $(UNIT))
+	@sed -e 's|@PREFIX@|$(PREFIX)|g' -e 's|@SERVICE_USER@|$(SERVICE_USER)|g' deploy/{{.name}}.service > $(UNIT)
+	@if [ -z "$(DESTDIR)" ]; then systemctl daemon-reload && systemctl enable --now {{.name}}.service; fi
+
+uninstall-service: phony ## stop the systemd unit and remove it with the binary
+	@if [ -z "$(DESTDIR)" ]; then systemctl disable --now {{.name}}.service || true; fi
+	@rm -f $(UNIT) $(DESTDIR)$(PREFIX)/bin/{{.name}}
+	@if [ -z "$(DESTDIR)" ]; then systemctl daemon-reload; fi
+{{- end}}
+{{end}}
+
+{{define "goreleaser"}}
+{{- if .goreleaser}}
+# GORELEASER_FLAGS are passed to goreleaser build, which builds a snapshot of the
+# working tree with SNAPSHOT=1 instead of requiring a tag.
+GORELEASER_FLAGS = --clean$(if $(SNAPSHOT), --snapshot)
+
+goreleaser: phony $(BIN)/.goreleaser-$(GORELEASER_VERSION) ## build the release artifacts of every binary with goreleaser
+	@$(BIN)/goreleaser build $(GORELEASER_FLAGS)
+{{- range .meta.BinaryNames}}
+
+release-{{.}}: phony $(BIN)/.goreleaser-$(GORELEASER_VERSION) ## build the {{.}} release artifacts with goreleaser
+	@$(BIN)/goreleaser build $(GORELEASER_FLAGS) --id {{.}}
+{{- end}}
+{{- end}}
+{{end}}
+
+{{define "dist"}}
+{{- if .release}}
+# PLATFORMS are the GOOS/GOARCH pairs dist cross-compiles the binary for.
+PLATFORMS ?= linux/amd64 linux/arm64 darwin/amd64 darwin/arm64 windows/amd64
+DIST = $(BIN)/dist
+
+dist: phony ## build the release binaries of every platform with their checksums
+	@rm -rf $(DIST) && mkdir -p $(DIST)
+	@for platform in $(PLATFORMS); do \
+		os=$${platform%/*}; arch=$${platform#*/}; ext=; \
+		if [ "$$os" = windows ]; then ext=.exe; fi; \
+		CGO_ENABLED=0 GOOS=$$os GOARCH=$$arch go build \
+			-tags release \
+			-trimpath \
+			-ldflags '-X main.Version=$(VERSION)' \
+			-o $(DIST)/{{.name}}_$(VERSION)_$${os}_$${arch}$$ext . || exit 1; \
+	done
+	@cd $(DIST) && sha256sum {{.name}}_* > checksums.txt
+{{- end}}
+{{end}}
+
+{{define "tag"}}
+{{- if .release}}
+# tag pushes the annotated tag TAG, which the release workflow publishes.
+tag: phony ## tag and push the release TAG (make tag TAG=v1.2.3)
+	@if [ -z "$(TAG)" ]; then echo "TAG is required, run make tag TAG=v1.2.3"; exit 1; fi
+	@git tag -a $(TAG) -m "Release $(TAG)"
+	@git push origin $(TAG)
+{{- end}}
+{{end}}
+
+{{define "release-notes"}}
+{{- if .release}}
+# RELEASE_NOTES is the draft release-notes writes from the conventional commits
+# since the tag before TAG, or before HEAD without it, grouped by their type. The
+# release workflow publishes it as the notes of the release.
+RELEASE_NOTES = dist/release-notes.md
+
+release-notes: phony ## draft the release notes of the commits since the last tag
+	@mkdir -p $(dir $(RELEASE_NOTES))
+	@ref=$(or $(TAG),HEAD); \
+	last=$$(git describe --tags --abbrev=0 $$ref^ 2>/dev/null); \
+	git log --no-merges --format='%x1e%s%x09%b' $${last:+$$last..}$$ref | awk -v RS='\036' -F '\t' -v title="$(or $(TAG),Unreleased)" ' \
+		$$1 == "" { next } \
+		{ type = "other"; subject = $$1 } \
+		match($$1, /^[a-z]+(\([^)]*\))?!?: /) { \
+			type = substr($$1, 1, RLENGTH - 2); subject = substr($$1, RLENGTH + 1); \
+			if (type ~ /!$$/ || substr($$0, length($$1) + 1) ~ /BREAKING CHANGE/) type = "breaking"; \
+			else { sub(/\(.*/, "", type) } } \
+		{ notes[type] = notes[type] "- " subject "\n" } \
+		END { \
+			print "# " title; \
+			n = split("breaking:Breaking changes,feat:Features,fix:Bug fixes,perf:Performance,docs:Documentation", groups, ","); \
+			for (i = 1; i <= n; i++) { \
+				split(groups[i], group, ":"); \
+				if (group[1] in notes) { printf "\n## %s\n\n%s", group[2], notes[group[1]]; delete notes[group[1]] } } \
+			for (type in notes) other = other notes[type]; \
+			if (other != "") printf "\n## Other changes\n\n%s", other }' > $(RELEASE_NOTES)
+	@echo "wrote $(RELEASE_NOTES)"
+{{- end}}
+{{end}}
+
+{{define "all"}}
+all: phony {{if eq .makeFeatures "4.x"}}check-make {{end}}generate build{{if .test}} test{{end}} fmt lint vet
+{{- if eq .lang "go+node"}} npm-build{{end}}
+{{- if eq .lang "go+python"}} py-lint{{end}} ## generate, build, test and lint the codes
+
+# When all is a goal, order its stages so they can run with make -j. fmt rewrites
+# sources, so it runs on its own before lint and vet check them concurrently.
+ifneq ($(filter all,$(MAKECMDGOALS)),)
+build: | generate
+{{- if .test}}
+test: | build
+fmt: | test
+{{- else}}
+fmt: | build
+{{- end}}
+lint vet: | fmt
+endif
+{{end}}
+
+{{define "check-make"}}
+{{- if eq .makeFeatures "4.x"}}
+check-make: phony ## check GNU Make is at least MAKE_MIN_VERSION
+	@if [ "$$(printf '%s\n' $(MAKE_MIN_VERSION) $(MAKE_VERSION) | sort -t. -k1,1n -k2,2n | head -n1)" != "$(MAKE_MIN_VERSION)" ]; then
+		echo "GNU Make $(MAKE_MIN_VERSION) or newer is required, found $(MAKE_VERSION)"
+		exit 1
+	fi
+{{- end}}
+{{end}}
+
+{{define "makefile-test"}}
+# REQUIRED_COMMANDS are the commands the recipes run that make bootstrap does
+# not install into BIN.
+REQUIRED_COMMANDS = {{join (commands .) " "}}
+
+# makefile-test dry-runs every documented target, which fails when a recipe
+# cannot expand or a prerequisite cannot be made, so the tools in BIN resolve
+# through their sentinels. The commands the recipes need must be on PATH.
+makefile-test: phony ## dry-run every target and check the commands they need resolve
+	@status=0; \
+	for command in $(REQUIRED_COMMANDS); do \
+		command -v $$command >/dev/null 2>&1 || { echo "$$command is not on PATH"; status=1; }; \
+	done; \
+	for target in $$(awk -F ':' '/^[a-zA-Z0-9_.-]+:.*##/ && $$1 != "makefile-test" { print $$1 }' $(MAKEFILE_LIST)); do \
+		if ! out=$$($(MAKE) --no-print-directory -n $$target 2>&1 >/dev/null); then \
+			echo "$$target does not dry-run:"; echo "$$out" | sed 's/^/  /'; status=1; \
+		fi; \
+	done; \
+	exit $$status
+{{end}}
+
+{{define "requirements"}}
+# requirements reads the requires comments above the targets, which list the
+# commands their recipes run, and prints those missing from PATH with the
+# targets needing them and how to install them.
+requirements: phony ## print the commands the targets need that are not on PATH
+	@awk '/^# requires:/ { n = split(substr($$0, 13), commands, " "); getline; sub(/:.*/, ""); \
+		for (i = 1; i <= n; i++) needs[commands[i]] = needs[commands[i]] " " $$0 } \
+		END { for (c in needs) print c needs[c] }' $(MAKEFILE_LIST) | sort | { \
+	status=0; \
+	while read -r command targets; do \
+		command -v $$command >/dev/null 2>&1 && continue; \
+		echo "$$command is not on PATH, needed by $$targets"; \
+		case $$command in \
+{{- range hints .}}
+		{{.command}}) echo "  {{.hint}}" ;; \
+{{- end}}
+		esac; \
+		status=1; \
+	done; \
+	exit $$status; }
+{{end}}
+
+{{define "help"}}
+# help only colors its output when writing to a terminal tput knows and NO_COLOR
+# is unset, so CI logs stay free of escape sequences.
+help: phony ## print this help message
+	@green=; reset=; \
+	if [ -t 1 ] && [ -z "$${NO_COLOR:-}" ] && [ -n "$${TERM:-}" ] && [ "$${TERM:-}" != dumb ] && command -v tput >/dev/null 2>&1; then \
+		green=$$(tput setaf 2 2>/dev/null); reset=$$(tput sgr0 2>/dev/null); \
+	fi; \
+	awk -F ':|##' -v green="$$green" -v reset="$$reset" \
+		'/^[^\t].+?:.*?##/ { printf "%s%-20s%s%s\n", green, $$1, reset, $$NF }' $(MAKEFILE_LIST)
+{{end}}
+
+{{define "help-json"}}
+# help-json prints the targets with their descriptions and the variables that
+# can be overridden with their defaults, for IDE task runners and portals.
+help-json: phony ## print the targets and variables as JSON
+	@awk 'function str(s,  out, c, i) { for (i = 1; i <= length(s); i++) { c = substr(s, i, 1); if (c == "\\" || c == "\"") c = "\\" c; else if (c == "\t") c = "\\t"; out = out c }; return "\"" out "\"" } \
+		{ line = line $$0 } /\\$$/ { sub(/\\$$/, " ", line); next } \
+		line ~ /^[^\t#]+:.*##/ { name = line; sub(/:.*/, "", name); help = line; sub(/^[^#]*## */, "", help); \
+			targets = targets sep "{\"name\":" str(name) ",\"description\":" str(help) "}"; sep = "," } \
+		line ~ /^(export +)?[A-Za-z_][A-Za-z0-9_]* *\?=/ { name = line; sub(/^export +/, "", name); sub(/ *\?=.*/, "", name); value = line; sub(/^[^?]*\?= */, "", value); gsub(/[ \t]+/, " ", value); \
+			variables = variables vsep "{\"name\":" str(name) ",\"default\":" str(value) "}"; vsep = "," } \
+		{ line = "" } \
+		END { printf "{\"targets\":[%s],\"variables\":[%s]}\n", targets, variables }' $(MAKEFILE_LIST)
+{{end}}
+`
+
+// fileTemplates are the built-in templates for the files generated alongside
+// the Makefile, keyed by the name they are rendered under.
+var fileTemplates = map[string]string{
+	"main.go": `package main
+{{- if eq .type "http"}}
+
+import (
+	"log"
+	"net/http"
+	"os"
+)
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "{{.meta.Port}}"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	log.Printf("listening on :%s", port)
+	log.Fatal(http.ListenAndServe(":"+port, mux))
+}
+{{- else}}
+
+func main() {
+}
+{{- end}}
+`,
+	"library.go": `package {{.meta.Package}}
+`,
+	"doc.go": `// {{.meta.Description}}
+{{- if .meta.Homepage}}
+//
+// See {{.meta.Homepage}}.
+{{- end}}
+package {{if .library}}{{.meta.Package}}{{else}}main{{end}}
+`,
+	"README.md": `# {{.name}}
+{{- if and .ci .pipeline.Badge}}
+
+[![ci]({{.pipeline.Badge}})]({{.vcs.URL}})
+{{- end}}
+
+{{.meta.Description}}
+{{- if .meta.Keywords}}
+
+Keywords: {{join .meta.Keywords ", "}}
+{{- end}}
+{{- if .meta.Homepage}}
+
+Homepage: <{{.meta.Homepage}}>
+{{- end}}
+
+## Development
+
+{{- if eq .type "monorepo"}}
+
+Add a service with ` + "`maker add-service NAME`" + `, then run ` + "`make help`" + ` for the targets
+that run across the modules.
+{{- else}}
+
+Install the tools pinned in tools.yaml with ` + "`make bootstrap`" + `, then run
+` + "`make help`" + ` for the other targets.
+
+## Targets
+{{range tasks .}}
+- ` + "`make {{.Name}}`" + `: {{tr $.catalog .Description}}
+{{- end}}
+{{- end}}
+`,
+	"example_test.go": `package {{.meta.Package}}
+
+import "fmt"
+
+// Example is rendered as the package example on pkg.go.dev, and go test checks
+// that it prints its Output comment.
+func Example() {
+	fmt.Println("{{.name}}")
+	// Output: {{.name}}
+}
+`,
+	"golden_test.go": `package {{if .library}}{{.meta.Package}}{{else}}main{{end}}
+
+import (
+{{- if eq .assertions "stdlib"}}
+	"bytes"
+{{- end}}
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+{{- if eq .assertions "testify"}}
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+{{- else if eq .assertions "gotest.tools"}}
+
+	"gotest.tools/v3/assert"
+{{- end}}
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with the golden file testdata/NAME.golden, or rewrites
+// the file with got when the tests run with -update.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", filepath.FromSlash(name)+".golden")
+{{- if eq .assertions "testify"}}
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(path, got, 0644))
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "run go test -update to create it")
+	assert.Equal(t, string(want), string(got), "%s does not match, run go test -update to accept the change", path)
+{{- else if eq .assertions "gotest.tools"}}
+	if *update {
+		assert.NilError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.NilError(t, ioutil.WriteFile(path, got, 0644))
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	assert.NilError(t, err, "run go test -update to create it")
+	assert.Equal(t, string(got), string(want), "%s does not match, run go test -update to accept the change", path)
+{{- else}}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run go test -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match, run go test -update to accept the change\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+{{- end}}
+}
+`,
+	"starter_test.go": `package {{if .library}}{{.meta.Package}}{{else}}main{{end}}
+
+import "testing"
+
+// TestName is table driven: add a case to tests for each behavior to check, and
+// each case runs as its own subtest with a golden file under testdata/TestName.
+func TestName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"name", "{{.name}}"},
+	}
+	for _, tt := range tests {
+{{- if .parallelTests}}
+		tt := tt
+{{- end}}
+		t.Run(tt.name, func(t *testing.T) {
+{{- if .parallelTests}}
+			t.Parallel()
+{{- end}}
+			golden(t, t.Name(), []byte(tt.in+"\n"))
+		})
+	}
+}
+`,
+	"testdata/TestName/name.golden": `{{.name}}
+`,
+	"bench_test.go": `package {{if .library}}{{.meta.Package}}{{else}}main{{end}}
+
+import (
+	"fmt"
+	"testing"
+)
+
+// BenchmarkCopy measures copying inputs of increasing size, one sub-benchmark
+// per size. Replace the loop body with the code to benchmark.
+func BenchmarkCopy(b *testing.B) {
+	for _, size := range []int{16, 1 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			input := make([]byte, size)
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = append([]byte(nil), input...)
+			}
+		})
+	}
+}
+`,
+	"go.mod": `module {{.module}}
+
+go {{.minGoVersion}}
+{{- if and .test (eq .assertions "testify")}}
+
+require github.com/stretchr/testify v1.9.0
+{{- else if and .test (eq .assertions "gotest.tools")}}
+
+require gotest.tools/v3 v3.5.1
+{{- end}}
+`,
+	"workspace/Makefile": `.DEFAULT_GOAL := help
+{{- if eq .shell "bash"}}
+
+SHELL := bash
+.SHELLFLAGS := -eu -o pipefail -c
+{{- else if eq .shell "sh"}}
+
+SHELL := /bin/sh
+.SHELLFLAGS := -ec
+{{- end}}
+
+# MODULES are the module directories the -all targets run in, read from go.work
+# or found by scanning for go.mod files below the root.
+MODULES ?= $(shell if [ -f go.work ]; then \
+		go list -m -f '{{"{{.Dir}}"}}' | grep -vx '$(CURDIR)' | sed 's|^$(CURDIR)/||'; \
+	else \
+		find . -mindepth 2 -name go.mod -not -path '*/.*' | xargs -n1 dirname | sed 's|^\./||' | sort; \
+	fi)
+
+BASE ?= origin/main
+
+# CHANGED_MODULES are the modules with files changed since BASE and the modules
+# depending on them.
+CHANGED_MODULES = $(shell BASE='$(BASE)' MODULES='$(MODULES)' ./scripts/changed-modules.sh)
+
+# run-all runs the target $(1) in every module of $(2), failing once all of them
+# ran if any of them failed.
+define run-all
+	@failed=""; \
+	for module in $(2); do \
+		echo "==> $$module: make $(1)"; \
+		$(MAKE) -C $$module $(1) || failed="$$failed $$module"; \
+	done; \
+	if [ -n "$$failed" ]; then echo "make $(1) failed in:$$failed"; exit 1; fi
+endef
+
+.PHONY:phony
+
+modules: phony ## list the modules
+	@for module in $(MODULES); do echo $$module; done
+
+build-all: phony ## build every module
+	$(call run-all,build,$(MODULES))
+
+lint-all: phony ## lint every module
+	$(call run-all,lint,$(MODULES))
+
+test-all: phony ## test every module
+	$(call run-all,test,$(MODULES))
+
+# The modules pinning their tools in tools.yaml install them into bin, so each
+# tool is installed once for the workspace.
+bootstrap: phony ## install the tools pinned in tools.yaml for every module
+	$(call run-all,bootstrap,$(MODULES))
+
+changed: phony ## list the modules affected by changes since BASE
+	@for module in $(CHANGED_MODULES); do echo $$module; done
+
+build-changed: phony ## build the modules affected by changes since BASE
+	$(call run-all,build,$(CHANGED_MODULES))
+
+lint-changed: phony ## lint the modules affected by changes since BASE
+	$(call run-all,lint,$(CHANGED_MODULES))
+
+test-changed: phony ## test the modules affected by changes since BASE
+	$(call run-all,test,$(CHANGED_MODULES))
+
+# help only colors its output when writing to a terminal tput knows and NO_COLOR
+# is unset, so CI logs stay free of escape sequences.
+help: phony ## print this help message
+	@green=; reset=; \
+	if [ -t 1 ] && [ -z "$${NO_COLOR:-}" ] && [ -n "$${TERM:-}" ] && [ "$${TERM:-}" != dumb ] && command -v tput >/dev/null 2>&1; then \
+		green=$$(tput setaf 2 2>/dev/null); reset=$$(tput sgr0 2>/dev/null); \
+	fi; \
+	awk -F ':|##' -v green="$$green" -v reset="$$reset" \
+		'/^[^\t].+?:.*?##/ { printf "%s%-20s%s%s\n", green, $$1, reset, $$NF }' $(MAKEFILE_LIST)
+
+# help-json prints the targets with their descriptions and the variables that
+# can be overridden with their defaults, for IDE task runners and portals.
+help-json: phony ## print the targets and variables as JSON
+	@awk 'function str(s,  out, c, i) { for (i = 1; i <= length(s); i++) { c = substr(s, i, 1); if (c == "\\" || c == "\"") c = "\\" c; else if (c == "\t") c = "\\t"; out = out c }; return "\"" out "\"" } \
+		{ line = line $$0 } /\\$$/ { sub(/\\$$/, " ", line); next } \
+		line ~ /^[^\t#]+:.*##/ { name = line; sub(/:.*/, "", name); help = line; sub(/^[^#]*## */, "", help); \
+			targets = targets sep "{\"name\":" str(name) ",\"description\":" str(help) "}"; sep = "," } \
+		line ~ /^(export +)?[A-Za-z_][A-Za-z0-9_]* *\?=/ { name = line; sub(/^export +/, "", name); sub(/ *\?=.*/, "", name); value = line; sub(/^[^?]*\?= */, "", value); gsub(/[ \t]+/, " ", value); \
+			variables = variables vsep "{\"name\":" str(name) ",\"default\":" str(value) "}"; vsep = "," } \
+		{ line = "" } \
+		END { printf "{\"targets\":[%s],\"variables\":[%s]}\n", targets, variables }' $(MAKEFILE_LIST)
+`,
+	"workspace/changed-modules.sh": `#!/bin/sh
+# changed-modules.sh prints the modules of MODULES with files changed since the
+# BASE ref, followed by the modules depending on them.
+set -eu
+
+base=${BASE:-origin/main}
+modules=${MODULES:?MODULES must list the module directories}
+files=$(git diff --name-only "$base"...HEAD)
+
+if echo "$files" | grep -qx 'go.work'; then
+	for module in $modules; do echo "$module"; done
+	exit 0
+fi
+
+changed=""
+paths=""
+for module in $modules; do
+	if echo "$files" | grep -q "^$module/"; then
+		changed="$changed $module"
+		paths="$paths $(awk '$1 == "module" { print $2 }' "$module/go.mod")"
+		echo "$module"
+	fi
+done
+
+for module in $modules; do
+	case " $changed " in *" $module "*) continue ;; esac
+	deps=$(cd "$module" && go list -deps -f '{{"{{with .Module}}{{.Path}}{{end}}"}}' ./... 2>/dev/null | sort -u)
+	for path in $paths; do
+		if echo "$deps" | grep -qx "$path"; then
+			echo "$module"
+			break
+		fi
+	done
+done
+`,
+	"systemd.service": `# Rendered by make install-service, which replaces @PREFIX@ and @SERVICE_USER@.
+[Unit]
+Description={{.meta.Description}}
+{{- if .meta.Homepage}}
+Documentation={{.meta.Homepage}}
+{{- end}}
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+User=@SERVICE_USER@
+ExecStart=@PREFIX@/bin/{{.name}}
+Environment=PORT={{.meta.Port}}
+Restart=on-failure
+NoNewPrivileges=true
+ProtectSystem=strict
+ProtectHome=true
+PrivateTmp=true
+
+[Install]
+WantedBy=multi-user.target
+`,
+	"ci.yml": `# Runs the pipeline on every push and pull request with each go version of the
+# matrix. minimum is the go directive of go.mod, which GOTOOLCHAIN=local keeps
+# from being upgraded, and tip is allowed to fail.
+name: ci
+
+on:
+  push:
+    branches:
+      - main
+  pull_request:
+
+jobs:
+  test:
+    name: go {{"${{ matrix.go }}"}}
+    runs-on: ubuntu-latest
+    continue-on-error: {{"${{ matrix.go == 'tip' }}"}}
+    strategy:
+      fail-fast: false
+      matrix:
+        go:
+{{- range split .goVersions ","}}
+          - "{{.}}"
+{{- end}}
+    env:
+      GOTOOLCHAIN: local
+{{- range .pipeline.Caches}}
+      {{.Env}}: {{"${{ github.workspace }}"}}/{{.Path}}
+{{- end}}
+    steps:
+      - uses: actions/checkout@v4
+      - if: matrix.go == 'minimum'
+        uses: actions/setup-go@v5
+        with:
+          go-version-file: go.mod
+          cache: false
+      - if: matrix.go != 'minimum' && matrix.go != 'tip'
+        uses: actions/setup-go@v5
+        with:
+          go-version: {{"${{ matrix.go }}"}}
+          cache: false
+      - if: matrix.go == 'tip'
+        uses: actions/setup-go@v5
+        with:
+          go-version: stable
+          cache: false
+      - name: Install tip
+        if: matrix.go == 'tip'
+        run: |
+          go install golang.org/dl/gotip@latest
+          gotip download
+          echo "$HOME/sdk/gotip/bin" >> "$GITHUB_PATH"
+      - uses: actions/cache@v4
+        with:
+          path: |
+{{- range .pipeline.Caches}}
+            {{.Path}}
+{{- end}}
+          key: go-{{"${{ runner.os }}-${{ matrix.go }}-${{ hashFiles('go.mod', 'go.sum') }}"}}
+      - run: go version
+{{- range .pipeline.Stages}}
+{{- $stage := .Name}}
+{{- range .Targets}}
+      - name: {{$stage}} / {{.}}
+        run: make {{.}}
+{{- end}}
+{{- end}}
+{{- with .pipeline.Artifacts}}
+      - uses: actions/upload-artifact@v4
+        with:
+          name: {{"ci-${{ matrix.go }}"}}
+          path: |
+{{- range .}}
+            {{.}}
+{{- end}}
+{{- end}}
+`,
+	"gitlab-ci.yml": `# Runs the pipeline on every push and merge request in the golang image of each
+# go version of the matrix, one job per stage. minimum is the go directive of
+# go.mod.
+stages:
+{{- range .pipeline.Stages}}
+  - {{.Name}}
+{{- end}}
+
+.go:
+  image: golang:$GO
+  parallel:
+    matrix:
+      - GO:
+{{- range split .goVersions ","}}
+          - "{{goImage . $.minGoVersion}}"
+{{- end}}
+  variables:
+    GOTOOLCHAIN: local
+{{- range .pipeline.Caches}}
+    {{.Env}}: $CI_PROJECT_DIR/{{.Path}}
+{{- end}}
+  cache:
+    key: go-$GO
+    paths:
+{{- range .pipeline.Caches}}
+      - {{.Path}}
+{{- end}}
+  before_script:
+    - go version
+{{- range .pipeline.Stages}}
+
+{{.Name}}:
+  extends: .go
+  stage: {{.Name}}
+  script:
+{{- range .Targets}}
+    - make {{.}}
+{{- end}}
+{{- if and (eq .Name "test") $.pipeline.Artifacts}}
+  artifacts:
+    paths:
+{{- range $.pipeline.Artifacts}}
+      - {{.}}
+{{- end}}
+{{- end}}
+{{- end}}
+`,
+	"bitbucket-pipelines.yml": `# Runs the pipeline on every push in the golang image of each go version of
+# the matrix. minimum is the go directive of go.mod.
+definitions:
+  caches:
+{{- range .pipeline.Caches}}
+    {{.Name}}: {{.Path}}
+{{- end}}
+
+pipelines:
+  default:
+    - parallel:
+{{- range split .goVersions ","}}
+        - step:
+            name: go {{.}}
+            image: golang:{{goImage . $.minGoVersion}}
+            caches:
+{{- range $.pipeline.Caches}}
+              - {{.Name}}
+{{- end}}
+            script:
+              - export GOTOOLCHAIN=local
+{{- range $.pipeline.Caches}}
+              - export {{.Env}}=$BITBUCKET_CLONE_DIR/{{.Path}}
+{{- end}}
+              - go version
+{{- range $.pipeline.Stages}}
+{{- range .Targets}}
+              - make {{.}}
+{{- end}}
+{{- end}}
+{{- with $.pipeline.Artifacts}}
+            artifacts:
+{{- range .}}
+              - {{.}}
+{{- end}}
+{{- end}}
+{{- end}}
+`,
+	"circleci.yml": `# Runs the pipeline on every push in the golang image of each go version of
+# the matrix. minimum is the go directive of go.mod.
+version: 2.1
+
+jobs:
+  pipeline:
+    parameters:
+      go:
+        type: string
+    docker:
+      - image: golang:<< parameters.go >>
+    working_directory: /root/project
+    environment:
+      GOTOOLCHAIN: local
+{{- range .pipeline.Caches}}
+      {{.Env}}: /root/project/{{.Path}}
+{{- end}}
+    steps:
+      - checkout
+      - restore_cache:
+          keys:
+            - go-<< parameters.go >>-{{"{{ checksum \"go.mod\" }}"}}
+      - run: go version
+{{- range .pipeline.Stages}}
+{{- $stage := .Name}}
+{{- range .Targets}}
+      - run:
+          name: {{$stage}} / {{.}}
+          command: make {{.}}
+{{- end}}
+{{- end}}
+      - save_cache:
+          key: go-<< parameters.go >>-{{"{{ checksum \"go.mod\" }}"}}
+          paths:
+{{- range .pipeline.Caches}}
+            - {{.Path}}
+{{- end}}
+{{- range .pipeline.Artifacts}}
+      - store_artifacts:
+          path: {{.}}
+{{- end}}
+
+workflows:
+  ci:
+    jobs:
+      - pipeline:
+          matrix:
+            parameters:
+              go:
+{{- range split .goVersions ","}}
+                - "{{goImage . $.minGoVersion}}"
+{{- end}}
+`,
+	"tasks.sh": `#!/bin/sh
+# Runs the targets of the Makefile without make, as in scripts/tasks.sh build
+# test. Tool versions are read from tools.yaml like the Makefile does.
+set -eu
+cd "$(dirname "$0")/.."
+
+VERSION=${VERSION:-$(git describe --tags --always --dirty --match='v*' 2> /dev/null || echo v0)}
+
+# tool_version prints the version of the tool $1 pinned in tools.yaml.
+tool_version() {
+	awk -F ': *' -v tool="$1" '$1 == tool { print $2 }' tools.yaml
+}
+{{- range usedTools .}}
+{{.variable}}={{printf "${%s:-$(tool_version %s)}" .variable .name}}
+{{- end}}
+
+usage() {
+	echo "Expected use: scripts/tasks.sh TASK..."
+	echo
+{{- range tasks .}}
+	echo "  {{printf "%-20s" .Name}}{{.Description}}"
+{{- end}}
+	echo "  {{printf "%-20s" "clean"}}remove the build outputs"
+}
+
+run_task() {
+	case "$1" in
+{{- range tasks .}}
+	{{.Name}})
+{{- range .Commands}}
+		{{.}}
+{{- end}}
+		;;
+{{- end}}
+	clean)
+		rm -rf bin
+		;;
+	*)
+		usage
+		exit 1
+		;;
+	esac
+}
+
+if [ $# -eq 0 ]; then
+	usage
+	exit 1
+fi
+for task in "$@"; do
+	run_task "$task"
+done
+`,
+	"tasks.ps1": `# Runs the targets of the Makefile without make, as in scripts/tasks.ps1 build
+# test. Tool versions are read from tools.yaml like the Makefile does.
+param([Parameter(ValueFromRemainingArguments = $true)][string[]]$Tasks)
+$ErrorActionPreference = 'Stop'
+Set-Location (Join-Path $PSScriptRoot '..')
+
+$VERSION = $env:VERSION
+if (-not $VERSION) {
+	$VERSION = git describe --tags --always --dirty --match='v*' 2> $null
+	if ($LASTEXITCODE -ne 0 -or -not $VERSION) { $VERSION = 'v0' }
+}
+
+# Get-ToolVersion returns the version of the tool Name pinned in tools.yaml.
+function Get-ToolVersion($Name) {
+	foreach ($line in Get-Content tools.yaml) {
+		if ($line -match "^$([regex]::Escape($Name)): *(.+)$") { return $Matches[1].Trim() }
+	}
+}
+{{- range usedTools .}}
+${{.variable}} = if ($env:{{.variable}}) { $env:{{.variable}} } else { Get-ToolVersion {{.name}} }
+{{- end}}
+
+function Show-Usage {
+	Write-Output 'Expected use: scripts/tasks.ps1 TASK...'
+	Write-Output ''
+{{- range tasks .}}
+	Write-Output '  {{printf "%-20s" .Name}}{{.Description}}'
+{{- end}}
+	Write-Output '  {{printf "%-20s" "clean"}}remove the build outputs'
+}
+
+# Invoke-Task runs a task, stopping at the first command that fails.
+function Invoke-Task($Name) {
+	switch ($Name) {
+{{- range tasks .}}
+		'{{.Name}}' {
+{{- range .Commands}}
+			{{.}}
+			if ($LASTEXITCODE -ne 0) { exit $LASTEXITCODE }
+{{- end}}
+		}
+{{- end}}
+		'clean' {
+			Remove-Item -Recurse -Force bin -ErrorAction SilentlyContinue
+		}
+		default {
+			Show-Usage
+			exit 1
+		}
+	}
+}
+
+if (-not $Tasks) {
+	Show-Usage
+	exit 1
+}
+foreach ($task in $Tasks) {
+	Invoke-Task $task
+}
+`,
+	"psakefile.ps1": `# Runs the targets of the Makefile with psake, as in Invoke-psake build. Tool
+# versions are read from tools.yaml like the Makefile does.
+
+# Get-ToolVersion returns the version of the tool Name pinned in tools.yaml.
+function Get-ToolVersion($Name) {
+	foreach ($line in Get-Content tools.yaml) {
+		if ($line -match "^$([regex]::Escape($Name)): *(.+)$") { return $Matches[1].Trim() }
+	}
+}
+
+Properties {
+	$VERSION = $env:VERSION
+	if (-not $VERSION) {
+		$VERSION = git describe --tags --always --dirty --match='v*' 2> $null
+		if ($LASTEXITCODE -ne 0 -or -not $VERSION) { $VERSION = 'v0' }
+	}
+{{- range usedTools .}}
+	${{.variable}} = if ($env:{{.variable}}) { $env:{{.variable}} } else { Get-ToolVersion {{.name}} }
+{{- end}}
+}
+
+Task default -Depends build
+{{range tasks .}}
+Task {{.Name}} -Description '{{.Description}}' {
+{{- range .Commands}}
+	exec { {{.}} }
+{{- end}}
+}
+{{end}}
+Task clean -Description 'remove the build outputs' {
+	Remove-Item -Recurse -Force bin -ErrorAction SilentlyContinue
+}
+`,
+	".goreleaser.yaml": `# goreleaser builds, archives{{if .docker}} and containerizes{{end}} every binary of
+# {{.name}}, each one under its own id.
+version: 2
+
+builds:
+{{- range .meta.BinaryNames}}
+  - id: {{.}}
+    binary: {{.}}
+    main: {{$.meta.Main .}}
+    env:
+      - CGO_ENABLED=0
+    flags:
+      - -trimpath
+    tags:
+      - release
+    ldflags:
+      - -X main.Version={{"{{ .Version }}"}}
+    goos:
+      - linux
+      - darwin
+      - windows
+    goarch:
+      - amd64
+      - arm64
+{{- end}}
+
+archives:
+{{- range .meta.BinaryNames}}
+  - id: {{.}}
+    ids:
+      - {{.}}
+    name_template: "{{.}}_{{"{{ .Version }}_{{ .Os }}_{{ .Arch }}"}}"
+{{- end}}
+{{- if .docker}}
+
+dockers:
+{{- range .meta.BinaryNames}}
+  - id: {{.}}
+    ids:
+      - {{.}}
+    dockerfile: Dockerfile.release
+    image_templates:
+      - "{{$.meta.Image}}{{if $.meta.Binaries}}{{$.meta.Separator}}{{lower .}}{{end}}:{{"{{ .Version }}"}}"
+    build_flag_templates:
+      - --build-arg=BINARY={{.}}
+      - --label=org.opencontainers.image.version={{"{{ .Version }}"}}
+      - --label=org.opencontainers.image.revision={{"{{ .FullCommit }}"}}
+{{- end}}
+{{- end}}
+
+checksum:
+  name_template: checksums.txt
+`,
+	"Dockerfile.release": `# Dockerfile.release packages a binary goreleaser has already built, which it
+# copies into the build context.
+FROM gcr.io/distroless/static-debian12
+ARG BINARY
+COPY ${BINARY} /app
+ENTRYPOINT ["/app"]
+`,
+	"release.yml": `# Publishes a GitHub release of the binaries built by make dist for every tag
+# pushed by make tag, with their checksums, a signed build provenance
+# attestation and the notes make release-notes drafts.
+name: release
+
+on:
+  push:
+    tags:
+      - "v*"
+
+permissions:
+  contents: write
+  id-token: write
+  attestations: write
+
+jobs:
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          fetch-depth: 0
+      - uses: actions/setup-go@v5
+        with:
+          go-version-file: go.mod
+      - name: Build
+        run: make dist VERSION="$GITHUB_REF_NAME"
+{{- if .imageScan}}
+      - name: Scan image
+        run: make image-scan VERSION="$GITHUB_REF_NAME"
+{{- end}}
+      - uses: actions/upload-artifact@v4
+        with:
+          name: dist
+          path: bin/dist/
+      - name: Attest provenance
+        uses: actions/attest-build-provenance@v1
+        with:
+          subject-path: bin/dist/{{.name}}_*
+      - name: Draft release notes
+        run: make release-notes TAG="$GITHUB_REF_NAME"
+      - name: Publish
+        env:
+          GH_TOKEN: {{"${{ github.token }}"}}
+        run: gh release create "$GITHUB_REF_NAME" bin/dist/* --title "$GITHUB_REF_NAME" --notes-file dist/release-notes.md
+`,
+	"Dockerfile": `{{$binary := .name}}{{$main := "."}}{{if .meta.Binaries}}{{$binary = "app"}}{{$main = "./cmd/${BINARY}"}}{{end -}}
+{{if .buildkitCache}}# syntax=docker/dockerfile:1
+{{end}}FROM golang:{{newerGo .minGoVersion "1.22"}} AS build
+
+WORKDIR /src
+
+# Download the modules in their own layer so it is only rebuilt when go.mod or
+# go.sum change.
+COPY go.mod go.sum* ./
+{{- if .buildkitCache}}
+RUN --mount=type=cache,target=/go/pkg/mod go mod download
+{{- else}}
+RUN go mod download
+{{- end}}
+
+COPY . .
+ARG VERSION=dev
+{{- if .meta.Binaries}}
+# BINARY is the binary of cmd/ the image runs.
+ARG BINARY={{index .meta.Binaries 0}}
+{{- end}}
+{{- if .buildkitCache}}
+RUN --mount=type=cache,target=/go/pkg/mod \
+	--mount=type=cache,target=/root/.cache/go-build \
+	CGO_ENABLED=0 go build -ldflags "-X main.Version=${VERSION}" -o /out/{{$binary}} {{$main}}
+{{- else}}
+RUN CGO_ENABLED=0 go build -ldflags "-X main.Version=${VERSION}" -o /out/{{$binary}} {{$main}}
+{{- end}}
+
+FROM gcr.io/distroless/static-debian12
+# make docker-build passes the provenance of the image as build args.
+ARG VERSION=dev
+ARG REVISION
+ARG CREATED
+ARG SOURCE{{if .vcs.Path}}={{.vcs.URL}}{{end}}
+{{- if .meta.Binaries}}
+ARG BINARY={{index .meta.Binaries 0}}
+LABEL org.opencontainers.image.title="${BINARY}" \
+{{- else}}
+LABEL org.opencontainers.image.title={{printf "%q" .name}} \
+{{- end}}
+	org.opencontainers.image.description={{printf "%q" .meta.Description}} \
+{{- if .meta.Homepage}}
+	org.opencontainers.image.url={{printf "%q" .meta.Homepage}} \
+{{- end}}
+	org.opencontainers.image.source="${SOURCE}" \
+	org.opencontainers.image.revision="${REVISION}" \
+	org.opencontainers.image.version="${VERSION}" \
+	org.opencontainers.image.created="${CREATED}"
+COPY --from=build /out/{{$binary}} /{{$binary}}
+{{- if eq .type "http"}}
+EXPOSE {{.meta.Port}}
+{{- end}}
+ENTRYPOINT ["/{{$binary}}"]
+`,
+	".dockerignore": `.git
+bin/
+.cache/
+`,
+	"go.work": `go {{.minGoVersion}}
+{{- if .module}}
+
+use .
+{{- end}}
+`,
+	"tools.yaml": `# Versions of the tools installed by make bootstrap. Bumping a version here
+# reinstalls the tool on its next use.
+{{- range tools .}}
+{{.name}}: {{.version}}
+{{- end}}
+`,
+	"licenses.allow": `# SPDX identifiers of the licenses dependencies may use, one per line, checked
+# by make licenses.
+Apache-2.0
+BSD-2-Clause
+BSD-3-Clause
+ISC
+MIT
+`,
+	".gitleaks.toml": `# The rules of make secrets-scan, the default rules of gitleaks along with
+# the files known to hold no secrets.
+title = "{{.name}}"
+
+[extend]
+useDefault = true
+
+[allowlist]
+description = "files known to hold no secrets"
+paths = [
+  '''go\.sum''',
+  '''\.gitleaks-baseline\.json''',
+]
+`,
+	".gitleaks-baseline.json": `[]
+`,
+	"pre-push": `#!/bin/sh
+# Refuses to push commits holding secrets. Run make install-hooks to enable it.
+exec make secrets-scan
+`,
+	".trivyignore": `# Vulnerabilities make image-scan accepts, one CVE or advisory ID per line,
+# each with a comment saying why.
+`,
+	"spellcheck.ignore": `# Words make spellcheck accepts as spelled, one per line.
+`,
+	".gitignore": `bin/
+.maker.snapshot.zip
+{{- if .release}}
+dist/
+{{- end}}
+{{- if .cache}}
+.cache/
+{{- end}}
+{{- if eq .lang "go+node"}}
+node_modules/
+web/dist/
+{{- end}}
+{{- if eq .lang "go+python"}}
+.venv/
+__pycache__/
+{{- end}}`,
+	"web/package.json": `{
+  "name": "{{.name}}-web",
+  "version": "0.0.0",
+  "private": true,
+  "description": {{printf "%q" .meta.Description}},
+{{- if .meta.Keywords}}
+  "keywords": [{{range $i, $keyword := .meta.Keywords}}{{if $i}}, {{end}}{{printf "%q" $keyword}}{{end}}],
+{{- end}}
+{{- if .meta.Homepage}}
+  "homepage": {{printf "%q" .meta.Homepage}},
+{{- end}}
+  "scripts": {
+    "build": "echo \"no frontend build configured\"",
+    "test": "echo \"no frontend tests configured\""
+  }
+}
+`,
+	"requirements-dev.txt": `# Python tools used by the makefile, installed into the virtualenv by make venv.
+ruff==0.6.9
+`,
+}
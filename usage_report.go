@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"runtime"
+	"time"
+)
+
+// reportFile is where -report writes the usage report, which nothing but the
+// user reads: maker never sends it anywhere.
+var reportFile string
+
+// usageReport records what a run of maker generated, for platform teams to
+// aggregate scaffolding usage themselves.
+type usageReport struct {
+	Maker     string    `json:"maker"`
+	Go        string    `json:"go"`
+	StartedAt time.Time `json:"startedAt"`
+	// DurationMS is how long the whole run took.
+	DurationMS int64           `json:"durationMs"`
+	Projects   []projectReport `json:"projects"`
+}
+
+// projectReport records one generated project.
+type projectReport struct {
+	Dir     string            `json:"dir"`
+	Type    string            `json:"type"`
+	Preset  string            `json:"preset,omitempty"`
+	Options []string          `json:"options"`
+	Files   []fileReport      `json:"files"`
+	Tools   map[string]string `json:"tools"`
+	// RenderMS and WriteMS are how long rendering the files into memory and
+	// writing them to disk took.
+	RenderMS int64 `json:"renderMs"`
+	WriteMS  int64 `json:"writeMs"`
+}
+
+// fileReport records one generated file.
+type fileReport struct {
+	Path  string `json:"path"`
+	Bytes int    `json:"bytes"`
+}
+
+// runReport collects the projects of this run when -report is set.
+var runReport *usageReport
+
+// startReport starts collecting the usage report when -report is set.
+func startReport() {
+	if reportFile != "" {
+		runReport = &usageReport{Maker: Version, Go: runtime.Version(), StartedAt: time.Now().UTC()}
+	}
+}
+
+// recordProject adds the project generated into dir from fsys to the usage
+// report, if one is being collected.
+func recordProject(dir string, data map[string]interface{}, fsys *memFS, render, write time.Duration) {
+	if runReport == nil {
+		return
+	}
+	project := projectReport{
+		Dir:      dir,
+		Type:     data["type"].(string),
+		Options:  []string{},
+		Tools:    map[string]string{},
+		RenderMS: render.Milliseconds(),
+		WriteMS:  write.Milliseconds(),
+	}
+	project.Preset, _ = data["preset"].(string)
+	for _, f := range enabledFeatures(data) {
+		project.Options = append(project.Options, f.name)
+	}
+	for _, path := range fsys.paths {
+		project.Files = append(project.Files, fileReport{path, len(fsys.files[path].contents)})
+	}
+	if data["type"] != "monorepo" {
+		for _, t := range enabledTools(data) {
+			project.Tools[t["name"]] = t["version"]
+		}
+	}
+	runReport.Projects = append(runReport.Projects, project)
+}
+
+// writeReport writes the usage report to reportFile, if one was collected.
+func writeReport() error {
+	if runReport == nil {
+		return nil
+	}
+	runReport.DurationMS = time.Since(runReport.StartedAt).Milliseconds()
+	out, err := json.MarshalIndent(runReport, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(reportFile, append(out, '\n'), 0644)
+}
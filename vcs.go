@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// vcsHost is a code host a project can live on, with the CI it runs.
+type vcsHost struct {
+	Name string
+	// Domain is the domain of the hosted service, empty for a self-hosted one
+	// whose domain is read from the module path.
+	Domain string
+	// CI is the name of the CI provider the host runs by default.
+	CI string
+	// subgroups is set for the hosts whose repositories can be nested in
+	// groups, so that the whole module path names the repository.
+	subgroups bool
+}
+
+// vcsHosts are the code hosts -vcs-host selects from.
+var vcsHosts = []vcsHost{
+	{Name: "github", Domain: "github.com", CI: "github"},
+	{Name: "gitlab", Domain: "gitlab.com", CI: "gitlab", subgroups: true},
+	{Name: "bitbucket", Domain: "bitbucket.org", CI: "bitbucket"},
+	{Name: "gitea", CI: "gitea"},
+}
+
+// majorVersion matches the major version suffix of a module path.
+var majorVersion = regexp.MustCompile(`/v\d+$`)
+
+// vcsRepo is the repository of a project on its host.
+type vcsRepo struct {
+	Host vcsHost
+	// Domain is the domain the repository is served from.
+	Domain string
+	// Path is the path of the repository on the host, as in team/project,
+	// empty when the module path does not name one.
+	Path string
+}
+
+// URL is the web page of the repository.
+func (r vcsRepo) URL() string {
+	return "https://" + r.Domain + "/" + r.Path
+}
+
+// RemoteURL is the SSH URL git pushes the repository to.
+func (r vcsRepo) RemoteURL() string {
+	return "git@" + r.Domain + ":" + r.Path + ".git"
+}
+
+// lookupVCSHost returns the code host with the name.
+func lookupVCSHost(name string) (vcsHost, bool) {
+	for _, h := range vcsHosts {
+		if h.Name == name {
+			return h, true
+		}
+	}
+	return vcsHost{}, false
+}
+
+// vcsHostNames returns the names of the code hosts.
+func vcsHostNames() []string {
+	names := make([]string, len(vcsHosts))
+	for i, h := range vcsHosts {
+		names[i] = h.Name
+	}
+	return names
+}
+
+// inferVCSHost returns the code host of a module path: the hosted service of
+// its domain, gitea for a domain starting with gitea. and github otherwise.
+func inferVCSHost(module string) vcsHost {
+	domain := strings.SplitN(module, "/", 2)[0]
+	for _, h := range vcsHosts {
+		if h.Domain != "" && h.Domain == domain {
+			return h
+		}
+	}
+	if strings.HasPrefix(domain, "gitea.") {
+		h, _ := lookupVCSHost("gitea")
+		return h
+	}
+	h, _ := lookupVCSHost("github")
+	return h
+}
+
+// setVCS sets the repository of the template data from -vcs-host, or the host
+// inferred from the module path when it is not set.
+func setVCS(data map[string]interface{}) error {
+	module, _ := data["module"].(string)
+	host := inferVCSHost(module)
+	if name, _ := data["vcsHost"].(string); name != "" {
+		var ok bool
+		if host, ok = lookupVCSHost(name); !ok {
+			return fmt.Errorf("unknown vcs host %q, expected one of %s", name, strings.Join(vcsHostNames(), ", "))
+		}
+	}
+	if github, _ := data["github"].(string); github != "" && host.Name != "github" {
+		return fmt.Errorf("-github conflicts with the %s vcs host, set -modulePrefix instead", host.Name)
+	}
+
+	repo := vcsRepo{Host: host, Domain: host.Domain}
+	parts := strings.Split(majorVersion.ReplaceAllString(module, ""), "/")
+	if host.Domain == "" {
+		repo.Domain = parts[0]
+	}
+	if parts[0] == repo.Domain && len(parts) >= 3 {
+		if host.subgroups {
+			repo.Path = strings.Join(parts[1:], "/")
+		} else {
+			repo.Path = strings.Join(parts[1:3], "/")
+		}
+	}
+	data["vcs"] = repo
+	return nil
+}
+
+// goImage returns the golang image tag of a go version of the CI matrix on
+// the providers that run CI jobs in images, where minimum is the go directive
+// of go.mod.
+func goImage(version, minimum string) string {
+	switch version {
+	case "minimum":
+		return minimum
+	case "stable":
+		return "latest"
+	}
+	return version
+}
+
+// newerGo returns the newer of the go versions a and b.
+func newerGo(a, b string) string {
+	if compareVersions(a, b) < 0 {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// verifyCommand runs maker verify, checking that a generated project builds
+// and that make can plan every target of its Makefile.
+func verifyCommand(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	flags.BoolVar(&plain, "plain", false, "Prints the checks without colors or glyphs")
+	flags.Parse(args)
+
+	if len(flags.Args()) > 1 {
+		fmt.Println("Expected use: maker verify [-plain] [DIR]")
+		os.Exit(1)
+	}
+	dir := "."
+	if len(flags.Args()) == 1 {
+		dir = flags.Arg(0)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	findings, err := verifyProject(ctx, dir)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	healthy := true
+	for _, f := range findings {
+		if f.ok {
+			report(passed, f.text)
+		} else {
+			report(failed, f.text)
+			healthy = false
+		}
+	}
+	if !healthy {
+		os.Exit(1)
+	}
+}
+
+// verifyProject copies the project in dir into a temporary directory and returns
+// whether go build and go vet pass in the copy and whether make -n plans each
+// target of its Makefile, so that nothing the checks run touches the project.
+// The go checks are left out of projects without a go.mod or go.work, and
+// go mod tidy runs first as the summary of a new project says to.
+func verifyProject(ctx context.Context, dir string) ([]finding, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "Makefile"))
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempDir("", "maker-verify-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := copyProject(dir, tmp); err != nil {
+		return nil, err
+	}
+
+	var findings []finding
+	check := func(text, name string, args ...string) bool {
+		if _, err := queryCommand(ctx, tmp, name, args...); err != nil {
+			findings = append(findings, finding{false, err.Error()})
+			return false
+		}
+		findings = append(findings, finding{true, text})
+		return true
+	}
+	switch {
+	case exists(filepath.Join(tmp, "go.mod")):
+		if check("go mod tidy", "go", "mod", "tidy") {
+			check("go build ./...", "go", "build", "./...")
+			check("go vet ./...", "go", "vet", "./...")
+		}
+	case exists(filepath.Join(tmp, "go.work")):
+		check("go build of the workspace", "go", "build", "./...")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	targets := makefileTargets(contents)
+	errs := forEach(len(targets), func(i int) error {
+		_, err := queryCommand(ctx, tmp, "make", "-n", targets[i])
+		return err
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for i, target := range targets {
+		if errs[i] != nil {
+			findings = append(findings, finding{false, errs[i].Error()})
+		} else {
+			findings = append(findings, finding{true, "make -n " + target})
+		}
+	}
+	return findings, nil
+}
+
+// copyProject copies the files of the project in dir into to, leaving out the
+// installed tools and dependencies make bootstrap would install again.
+func copyProject(dir, to string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+		switch {
+		case info.IsDir():
+			switch info.Name() {
+			case "bin", "node_modules", ".venv", ".cache":
+				if rel != "." {
+					return filepath.SkipDir
+				}
+			}
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+		return copyFile(p, target, info.Mode().Perm())
+	})
+}
+
+// copyFile copies the file from to the new file to with perm.
+func copyFile(from, to string, perm os.FileMode) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}